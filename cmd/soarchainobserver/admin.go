@@ -0,0 +1,490 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// exportFormatVersion identifies the NDJSON layout produced by exportData.
+// Bump it whenever a record's shape changes incompatibly.
+const exportFormatVersion = 1
+
+// exportRowsPerFlush bounds how many rows are written between flushes
+const exportRowsPerFlush = 500
+
+// importBatchSize is the number of records upserted per transaction
+const importBatchSize = 500
+
+// maxIngestRecords caps the number of earnings records per ingest request
+const maxIngestRecords = 1000
+
+// exportRecord is one NDJSON line of an export. The first line is always a
+// "header" record carrying the format version.
+type exportRecord struct {
+	Type    string          `json:"type"`
+	Version int             `json:"version,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// exportTable describes one table included in the export. Every table
+// openDatabaseWith migrates must be listed in exportTables.
+type exportTable struct {
+	recordType string
+	tableName  string
+	model      interface{}
+	newRow     func() interface{}
+	serialID   bool // whether the table has a serial "id" column
+}
+
+var exportTables = []exportTable{
+	{"client", "clients", &models.Client{}, func() interface{} { return &models.Client{} }, false},
+	{"client_earning", "client_earnings", &models.ClientEarning{}, func() interface{} { return &models.ClientEarning{} }, true},
+	{"epoch_earnings", "epoch_earnings", &models.EpochEarnings{}, func() interface{} { return &models.EpochEarnings{} }, true},
+	{"epoch_archive", "epoch_archive", &models.EpochArchive{}, func() interface{} { return &models.EpochArchive{} }, false},
+	{"processed_tx", "processed_txes", &models.ProcessedTx{}, func() interface{} { return &models.ProcessedTx{} }, false},
+	{"status_transition", "status_transitions", &models.StatusTransition{}, func() interface{} { return &models.StatusTransition{} }, true},
+	{"price_history", "price_history", &models.PriceHistory{}, func() interface{} { return &models.PriceHistory{} }, true},
+}
+
+// recomputeEpoch handles POST /api/v1/admin/epoch/:number/recompute?identifier=day
+// It rebuilds TotalEarnings of every epoch_earnings and epoch_archive row
+// with that epoch number from the client_earnings rows that fall inside the
+// row's epoch window. An archived epoch gets the whole total, so live rows
+// of it (from a backfill not yet archived) are dropped rather than counted
+// twice.
+func recomputeEpoch(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	epochNumber, err := strconv.ParseInt(c.Param("number"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "Invalid epoch number")
+		return
+	}
+	identifier, err := parseEpochIdentifier(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	recomputeArchive := `
+        UPDATE epoch_archive
+        SET total_earnings = (
+                SELECT COALESCE(SUM(ce.earnings), 0)
+                FROM client_earnings ce
+                WHERE ce.client_address = epoch_archive.client_address
+                  AND ce.timestamp >= epoch_archive.start_time
+                  AND ce.timestamp < epoch_archive.end_time
+            )
+        WHERE epoch_number = ? AND identifier = ?
+    `
+	dropArchived := `
+        DELETE FROM epoch_earnings
+        WHERE epoch_number = ? AND identifier = ? AND EXISTS (
+            SELECT 1 FROM epoch_archive a
+            WHERE a.client_address = epoch_earnings.client_address AND a.identifier = epoch_earnings.identifier
+              AND a.epoch_number = epoch_earnings.epoch_number AND a.start_time = epoch_earnings.start_time
+        )
+    `
+	recomputeLive := `
+        UPDATE epoch_earnings
+        SET total_earnings = (
+                SELECT COALESCE(SUM(ce.earnings), 0)
+                FROM client_earnings ce
+                WHERE ce.client_address = epoch_earnings.client_address
+                  AND ce.timestamp >= epoch_earnings.start_time
+                  AND ce.timestamp < epoch_earnings.end_time
+            ),
+            updated_at = ?
+        WHERE epoch_number = ? AND identifier = ?
+    `
+	var archived, live int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(recomputeArchive, epochNumber, identifier)
+		if result.Error != nil {
+			return result.Error
+		}
+		archived = result.RowsAffected
+		if err := tx.Exec(dropArchived, epochNumber, identifier).Error; err != nil {
+			return err
+		}
+		result = tx.Exec(recomputeLive, time.Now().UTC(), epochNumber, identifier)
+		live = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identifier":          identifier,
+		"epochNumber":         epochNumber,
+		"rowsUpdated":         live + archived,
+		"archivedRowsUpdated": archived,
+	})
+}
+
+// purgeClient handles DELETE /api/v1/admin/client/:address
+// It permanently removes a client (including a soft-deleted one) with all
+// its earnings and epoch rows in one transaction, for data-removal requests.
+// Rows stored under the client's solana address are only removed when no
+// other client uses that address.
+func purgeClient(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	address := c.Param("address")
+	if !coreAddressPattern.MatchString(address) {
+		respondError(c, http.StatusBadRequest, "Invalid client address")
+		return
+	}
+
+	deleted := gin.H{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var client models.Client
+		if err := tx.Unscoped().First(&client, "address = ?", address).Error; err != nil {
+			return err
+		}
+
+		// Earnings are keyed by the solana address, or the core address
+		// for clients without one.
+		earningsAddresses := []string{address}
+		if client.SolanaAddress != "" {
+			var sharing int64
+			if err := tx.Unscoped().Model(&models.Client{}).
+				Where("solana_address = ? AND address <> ?", client.SolanaAddress, address).
+				Count(&sharing).Error; err != nil {
+				return err
+			}
+			if sharing == 0 {
+				earningsAddresses = append(earningsAddresses, client.SolanaAddress)
+			}
+		}
+
+		for _, t := range []struct {
+			key   string
+			model interface{}
+		}{
+			{"clientEarnings", &models.ClientEarning{}},
+			{"epochEarnings", &models.EpochEarnings{}},
+			{"epochArchive", &models.EpochArchive{}},
+		} {
+			result := tx.Where("client_address IN ?", earningsAddresses).Delete(t.model)
+			if result.Error != nil {
+				return result.Error
+			}
+			deleted[t.key] = result.RowsAffected
+		}
+
+		result := tx.Where("client_address = ?", address).Delete(&models.StatusTransition{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["statusTransitions"] = result.RowsAffected
+
+		result = tx.Unscoped().Delete(&client)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted["clients"] = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "Client not found")
+			return
+		}
+		respondDBError(c, db, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"address": address,
+		"deleted": deleted,
+	})
+}
+
+// repairEarnings handles POST /api/v1/admin/repair-earnings
+// It moves earnings rows recorded under a client's core address, from before
+// its solana address was known, to that solana address. Rows stored under
+// an empty address can't be attributed to any client and are only counted.
+func repairEarnings(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var clients []models.Client
+	err := db.Unscoped().
+		Where("solana_address <> '' AND (" +
+			"EXISTS (SELECT 1 FROM client_earnings WHERE client_address = clients.address) OR " +
+			"EXISTS (SELECT 1 FROM epoch_earnings WHERE client_address = clients.address) OR " +
+			"EXISTS (SELECT 1 FROM epoch_archive WHERE client_address = clients.address))").
+		Find(&clients).Error
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	repaired := make([]string, 0, len(clients))
+	for _, client := range clients {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return blockchain.ReattributeEarnings(tx, client.Address, client.SolanaAddress)
+		})
+		if err != nil {
+			respondDBError(c, db, err)
+			return
+		}
+		repaired = append(repaired, client.Address)
+	}
+
+	var unattributed int64
+	if err := db.Model(&models.ClientEarning{}).Where("client_address = ''").Count(&unattributed).Error; err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"repairedClients":      repaired,
+		"unattributedEarnings": unattributed,
+	})
+}
+
+// ingestEarnings handles POST /api/v1/admin/earnings
+// It stores a JSON array of earnings records through the same path as the
+// live feed, e.g. to load history or test fixtures, and reports the outcome
+// of each record. Records are stored independently; a database failure
+// stops the request.
+func ingestEarnings(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	blockReader := c.MustGet("blockReader").(*blockchain.BlockReader)
+
+	var records []blockchain.EarningsRecord
+	if err := c.ShouldBindJSON(&records); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid earnings records: %v", err))
+		return
+	}
+	if len(records) > maxIngestRecords {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("At most %d records per request", maxIngestRecords))
+		return
+	}
+
+	epochs, err := blockReader.CurrentEpochs(c.MustGet("logger").(*log.Logger))
+	if err != nil {
+		c.Error(err)
+		respondError(c, http.StatusServiceUnavailable, "Epoch info unavailable")
+		return
+	}
+
+	results := make([]gin.H, 0, len(records))
+	var stored int
+	for i, record := range records {
+		result := gin.H{"index": i, "address": record.Address, "ok": false}
+		switch {
+		case !coreAddressPattern.MatchString(record.Address):
+			result["error"] = "Invalid client address"
+		case record.Timestamp.After(time.Now()):
+			result["error"] = "Timestamp is in the future"
+		default:
+			err := blockReader.IngestEarnings(record, epochs)
+			switch {
+			case err == nil:
+				result["ok"] = true
+				stored++
+			case errors.Is(err, blockchain.ErrInvalidEarnings):
+				result["error"] = err.Error()
+			default:
+				respondDBErrorWith(c, db, err, gin.H{"stored": stored, "results": results})
+				return
+			}
+		}
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stored":  stored,
+		"failed":  len(records) - stored,
+		"results": results,
+	})
+}
+
+// exportData handles GET /api/v1/admin/export
+// It streams every record of the tables in exportTables as NDJSON, reading
+// the tables row by row so the dataset never has to fit in memory.
+func exportData(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB).WithContext(c.Request.Context())
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("X-Export-Format-Version", strconv.Itoa(exportFormatVersion))
+
+	var (
+		table     int
+		rows      *sql.Rows
+		headerOut bool
+	)
+	defer func() {
+		if rows != nil {
+			rows.Close()
+		}
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		enc := json.NewEncoder(w)
+		if !headerOut {
+			headerOut = true
+			return enc.Encode(exportRecord{Type: "header", Version: exportFormatVersion}) == nil
+		}
+
+		current := exportTables[table]
+		if rows == nil {
+			var err error
+			if rows, err = db.Unscoped().Model(current.model).Rows(); err != nil {
+				c.Error(err)
+				return false
+			}
+		}
+
+		for i := 0; i < exportRowsPerFlush; i++ {
+			if !rows.Next() {
+				err := rows.Err()
+				rows.Close()
+				rows = nil
+				if err != nil {
+					c.Error(err)
+					return false
+				}
+				table++
+				return table < len(exportTables)
+			}
+
+			row := current.newRow()
+			if err := db.ScanRows(rows, row); err != nil {
+				c.Error(err)
+				return false
+			}
+			data, err := json.Marshal(row)
+			if err != nil {
+				c.Error(err)
+				return false
+			}
+			if err := enc.Encode(exportRecord{Type: current.recordType, Data: data}); err != nil {
+				// client went away
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// importData handles POST /api/v1/admin/import
+// It accepts the NDJSON produced by exportData and upserts the records in
+// batches, each batch in its own transaction. The header record must match
+// exportFormatVersion.
+func importData(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB).WithContext(c.Request.Context())
+	dec := json.NewDecoder(c.Request.Body)
+
+	var header exportRecord
+	if err := dec.Decode(&header); err != nil || header.Type != "header" {
+		respondError(c, http.StatusBadRequest, "Missing export header record")
+		return
+	}
+	if header.Version != exportFormatVersion {
+		respondError(c, http.StatusBadRequest,
+			fmt.Sprintf("Unsupported export format version %d (expected %d)", header.Version, exportFormatVersion))
+		return
+	}
+
+	tables := make(map[string]exportTable, len(exportTables))
+	counts := make(map[string]int, len(exportTables))
+	for _, t := range exportTables {
+		tables[t.recordType] = t
+		counts[t.recordType] = 0
+	}
+
+	// Records of one type are buffered until the batch is full or the type
+	// changes (exports are grouped by type).
+	var (
+		batchType string
+		batch     []interface{}
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, row := range batch {
+				if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(row).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		counts[batchType] += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for line := 2; ; line++ {
+		var record exportRecord
+		if err := dec.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			respondErrorWith(c, http.StatusBadRequest, fmt.Sprintf("Invalid record on line %d: %v", line, err), gin.H{"imported": counts})
+			return
+		}
+		table, ok := tables[record.Type]
+		if !ok {
+			respondErrorWith(c, http.StatusBadRequest, fmt.Sprintf("Unknown record type %q on line %d", record.Type, line), gin.H{"imported": counts})
+			return
+		}
+
+		row := table.newRow()
+		if err := json.Unmarshal(record.Data, row); err != nil {
+			respondErrorWith(c, http.StatusBadRequest, fmt.Sprintf("Invalid %s data on line %d: %v", record.Type, line, err), gin.H{"imported": counts})
+			return
+		}
+
+		if record.Type != batchType || len(batch) >= importBatchSize {
+			if err := flush(); err != nil {
+				respondDBErrorWith(c, db, err, gin.H{"imported": counts})
+				return
+			}
+			batchType = record.Type
+		}
+		batch = append(batch, row)
+	}
+	if err := flush(); err != nil {
+		respondDBErrorWith(c, db, err, gin.H{"imported": counts})
+		return
+	}
+
+	// Imported rows carry explicit ids, so move the serial sequences past
+	// them to keep future inserts from colliding. Other databases derive
+	// the next id from the rows.
+	for _, t := range exportTables {
+		if !t.serialID || db.Dialector.Name() != "postgres" {
+			continue
+		}
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %[1]s", t.tableName)
+		if err := db.Exec(query).Error; err != nil {
+			respondDBErrorWith(c, db, err, gin.H{"imported": counts})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imported": counts})
+}
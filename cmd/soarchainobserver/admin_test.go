@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const testAdminKey = "test-admin-key"
+
+// adminConfig is a config with the admin API enabled
+const adminConfig = `{"admin_api_key": "` + testAdminKey + `"}`
+
+// adminRequest sends an admin API request through a real server, which
+// streaming responses need.
+func adminRequest(t *testing.T, router *gin.Engine, method, path, body string) (*http.Response, []byte) {
+	t.Helper()
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-API-Key", testAdminKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+func TestExportTablesCoverSchema(t *testing.T) {
+	db := openTestDB(t, loadTestConfig(t, `{}`))
+
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := make(map[string]bool, len(exportTables))
+	for _, table := range exportTables {
+		exported[table.tableName] = true
+
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(table.model); err != nil {
+			t.Fatal(err)
+		}
+		if stmt.Schema.Table != table.tableName {
+			t.Errorf("%s: table name %q, but the model's table is %q", table.recordType, table.tableName, stmt.Schema.Table)
+		}
+	}
+	for _, table := range tables {
+		if !exported[table] && !strings.HasPrefix(table, "sqlite_") {
+			t.Errorf("table %s is migrated but not in exportTables", table)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	cfg := loadTestConfig(t, adminConfig)
+	source := openTestDB(t, cfg)
+
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, rows := range []interface{}{
+		&[]models.Client{
+			{Address: "soar1a", PubKey: "pk", SolanaAddress: "SoLa", TotalLifetimeEarnings: 3, LastChallengeTime: ts, CreatedAt: ts, ChallengeCount: 2},
+			{Address: "soar1gone", PubKey: "pk2", LastChallengeTime: ts, CreatedAt: ts, DeletedAt: gorm.DeletedAt{Time: ts, Valid: true}},
+		},
+		&[]models.ClientEarning{{ClientAddress: "SoLa", CoreAddress: "soar1a", Earnings: 1, Timestamp: ts}, {ClientAddress: "SoLa", CoreAddress: "soar1a", Earnings: 2, Timestamp: ts}},
+		&[]models.EpochEarnings{{ClientAddress: "SoLa", Identifier: "day", EpochNumber: 7, StartTime: ts, EndTime: ts.Add(24 * time.Hour), TotalEarnings: 3}},
+		&[]models.EpochArchive{{ClientAddress: "SoLa", Identifier: "day", EpochNumber: 1, StartTime: ts.Add(-144 * time.Hour), EndTime: ts.Add(-120 * time.Hour), TotalEarnings: 9}},
+		&[]models.ProcessedTx{{Hash: "TX1", Height: 10}, {Hash: "TX2", Height: 11}},
+		&[]models.StatusTransition{{ClientAddress: "soar1a", Status: "Up", Timestamp: ts}},
+		&[]models.PriceHistory{{Symbol: "SOAR", Timestamp: ts, USD: 0.25}},
+	} {
+		if err := source.Create(rows).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, export := adminRequest(t, newTestRouter(t, source, cfg), http.MethodGet, "/api/v1/admin/export", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("export status %d: %s", resp.StatusCode, export)
+	}
+	var types []string
+	scanner := bufio.NewScanner(strings.NewReader(string(export)))
+	for scanner.Scan() {
+		var record exportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid export line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, record.Type)
+	}
+	if len(types) != 11 || types[0] != "header" {
+		t.Fatalf("export records %v, want a header and 10 rows", types)
+	}
+
+	// Importing twice upserts rather than duplicating
+	target := openTestDB(t, cfg)
+	router := newTestRouter(t, target, cfg)
+	for run := 1; run <= 2; run++ {
+		resp, body := adminRequest(t, router, http.MethodPost, "/api/v1/admin/import", string(export))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("import run %d status %d: %s", run, resp.StatusCode, body)
+		}
+	}
+
+	for _, table := range exportTables {
+		var want, got int64
+		if err := source.Unscoped().Model(table.model).Count(&want).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := target.Unscoped().Model(table.model).Count(&got).Error; err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: imported %d rows, want %d", table.tableName, got, want)
+		}
+	}
+
+	var gone models.Client
+	if err := target.Unscoped().First(&gone, "address = ?", "soar1gone").Error; err != nil || !gone.DeletedAt.Valid {
+		t.Errorf("inactive client imported as %+v (%v), want it still inactive", gone, err)
+	}
+
+	// Later inserts get fresh ids
+	if err := target.Create(&models.StatusTransition{ClientAddress: "soar1a", Status: "Down", Timestamp: ts.Add(time.Hour)}).Error; err != nil {
+		t.Fatalf("insert after import: %v", err)
+	}
+}
+
+func TestImportRejectsInvalidExports(t *testing.T) {
+	cfg := loadTestConfig(t, adminConfig)
+	router := newTestRouter(t, openTestDB(t, cfg), cfg)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"no header", `{"type":"client","data":{}}`, "Missing export header record"},
+		{"other version", `{"type":"header","version":99}`, "Unsupported export format version 99 (expected 1)"},
+		{"unknown type", `{"type":"header","version":1}` + "\n" + `{"type":"wallet","data":{}}`, `Unknown record type \"wallet\" on line 2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := adminRequest(t, router, http.MethodPost, "/api/v1/admin/import", tt.body)
+			if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), tt.want) {
+				t.Errorf("status %d, body %s; want 400 mentioning %q", resp.StatusCode, body, tt.want)
+			}
+			// Errors carry the request id like every other error response
+			if !strings.Contains(string(body), `"requestId"`) {
+				t.Errorf("body %s has no request id", body)
+			}
+		})
+	}
+}
+
+func TestRepairEarnings(t *testing.T) {
+	cfg := loadTestConfig(t, adminConfig)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	now := time.Now().UTC()
+	if err := db.Create(&[]models.Client{
+		{Address: "soar1alice", SolanaAddress: testWallet, LastChallengeTime: now},
+		{Address: "soar1bob", LastChallengeTime: now}, // solana address still unknown
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&[]models.ClientEarning{
+		{ClientAddress: "soar1alice", Earnings: 100, Timestamp: now.Add(-time.Hour)}, // orphaned under the core address
+		{ClientAddress: testWallet, CoreAddress: "soar1alice", Earnings: 50, Timestamp: now},
+		{ClientAddress: "soar1bob", Earnings: 70, Timestamp: now},
+		{ClientAddress: "", Earnings: 30, Timestamp: now},
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	resp, body := adminRequest(t, router, http.MethodPost, "/api/v1/admin/repair-earnings", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	var result struct {
+		RepairedClients      []string
+		UnattributedEarnings int64
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.RepairedClients, []string{"soar1alice"}) || result.UnattributedEarnings != 1 {
+		t.Errorf("repaired %v with %d unattributed rows, want [soar1alice] with 1", result.RepairedClients, result.UnattributedEarnings)
+	}
+
+	var moved int64
+	if err := db.Model(&models.ClientEarning{}).Where("client_address = ? AND core_address = ?", testWallet, "soar1alice").
+		Count(&moved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Errorf("%d of soar1alice's rows under the wallet, want 2", moved)
+	}
+}
+
+func TestIngestEarnings(t *testing.T) {
+	epochStart := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	node := fakeChainNode(t, epochStart)
+	cfg := loadTestConfig(t, fakeChainConfig(node, `"admin_api_key": "`+testAdminKey+`"`))
+	db := openTestDB(t, cfg)
+	router := newTestRouterWithReader(t, db, cfg)
+
+	at := epochStart.Add(10 * time.Minute).Format(time.RFC3339)
+	records := `[
+		{"address": "soar1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq", "solanaAddress": "` + testWallet + `", "earnings": "1500usoar", "timestamp": "` + at + `", "epochNumber": 10},
+		{"address": "not-an-address", "earnings": "1usoar", "timestamp": "` + at + `", "epochNumber": 10},
+		{"address": "soar1pppppppppppppppppppppppppppppppppppppp", "earnings": "1usoar", "timestamp": "` + at + `", "epochNumber": 9},
+		{"address": "soar1pppppppppppppppppppppppppppppppppppppp", "earnings": "lots", "timestamp": "` + at + `", "epochNumber": 10},
+		{"address": "soar1pppppppppppppppppppppppppppppppppppppp", "earnings": "1usoar", "timestamp": "2999-01-01T00:00:00Z", "epochNumber": 10}
+	]`
+	resp, body := adminRequest(t, router, http.MethodPost, "/api/v1/admin/earnings", records)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	var result struct {
+		Stored, Failed int
+		Results        []struct {
+			Index int
+			OK    bool
+			Error string
+		}
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Stored != 1 || result.Failed != 4 || len(result.Results) != 5 {
+		t.Fatalf("result %s, want 1 stored and 4 failed", body)
+	}
+	wantErrors := []string{"", "Invalid client address", "not 9", "invalid earnings record", "Timestamp is in the future"}
+	for i, want := range wantErrors {
+		got := result.Results[i]
+		if got.Index != i || got.OK != (want == "") || !strings.Contains(got.Error, want) {
+			t.Errorf("record %d: %+v, want error containing %q", i, got, want)
+		}
+	}
+
+	// The stored record went through the live path: client, earnings and epoch
+	var client models.Client
+	if err := db.First(&client, "address = ?", "soar1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq").Error; err != nil {
+		t.Fatal(err)
+	}
+	if client.SolanaAddress != testWallet || client.TotalLifetimeEarnings != 1500 {
+		t.Errorf("client %+v", client)
+	}
+	var epoch models.EpochEarnings
+	if err := db.First(&epoch, "client_address = ? AND epoch_number = 10", testWallet).Error; err != nil || epoch.TotalEarnings != 1500 {
+		t.Errorf("epoch 10: %+v, %v; want 1500 earned", epoch, err)
+	}
+
+	resp, _ = adminRequest(t, router, http.MethodPost, "/api/v1/admin/earnings", `{"address": "soar1"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("non-array body: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestRecomputeArchivedEpoch(t *testing.T) {
+	cfg := loadTestConfig(t, adminConfig)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	day := func(n int) time.Time { return time.Date(2025, 1, n, 0, 0, 0, 0, time.UTC) }
+	earnings := []models.ClientEarning{
+		{ClientAddress: testWallet, Earnings: 1_000_000, Timestamp: day(2).Add(time.Hour)},
+		{ClientAddress: testWallet, Earnings: 500_000, Timestamp: day(2).Add(2 * time.Hour)},
+		{ClientAddress: testWallet, Earnings: 2_000_000, Timestamp: day(3).Add(time.Hour)},
+	}
+	// Epoch 2 is archived with a wrong total and has a late backfill row
+	// still live; epoch 3 is live with a wrong total
+	archived := models.EpochArchive{ClientAddress: testWallet, Identifier: "day", EpochNumber: 2,
+		StartTime: day(2), EndTime: day(3), TotalEarnings: 9_000_000}
+	live := []models.EpochEarnings{
+		{ClientAddress: testWallet, Identifier: "day", EpochNumber: 2, StartTime: day(2), EndTime: day(3), TotalEarnings: 500_000},
+		{ClientAddress: testWallet, Identifier: "day", EpochNumber: 3, StartTime: day(3), EndTime: day(4), TotalEarnings: 1},
+	}
+	for _, rows := range []interface{}{&earnings, &archived, &live} {
+		if err := db.Create(rows).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		epoch            string
+		updated, archive float64
+		want             float64
+	}{
+		{"2", 1, 1, 1.5},
+		{"3", 1, 0, 2},
+	} {
+		resp, data := adminRequest(t, router, http.MethodPost, "/api/v1/admin/epoch/"+tt.epoch+"/recompute", "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("epoch %s: status %d: %s", tt.epoch, resp.StatusCode, data)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["rowsUpdated"] != tt.updated || body["archivedRowsUpdated"] != tt.archive {
+			t.Errorf("epoch %s: %v, want %v rows updated, %v archived", tt.epoch, body, tt.updated, tt.archive)
+		}
+
+		reward := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miner/epoch?wallet="+testWallet+"&epoch="+tt.epoch, ""), http.StatusOK)
+		if reward["totalEarnings"] != tt.want {
+			t.Errorf("epoch %s earned %v after the recompute, want %v", tt.epoch, reward["totalEarnings"], tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// microAmount is an earnings amount in micro-units (usoar). It marshals as a
+// decimal string so values above 2^53 survive JSON parsers that read numbers
+// as float64.
+type microAmount int64
+
+func (a microAmount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(strconv.FormatInt(int64(a), 10))), nil
+}
+
+// amountFormatting describes how token amounts are rendered in responses.
+type amountFormatting struct {
+	tokenDecimals int  // decimals between the earnings denom and a token
+	decimals      int  // decimal places rendered, at most tokenDecimals
+	asString      bool // quote amounts so parsers don't round them
+}
+
+// newAmountFormatting returns the amount settings of cfg.
+func newAmountFormatting(cfg *config.Config) amountFormatting {
+	return amountFormatting{
+		tokenDecimals: cfg.TokenDecimals,
+		decimals:      cfg.DisplayDecimals(),
+		asString:      cfg.AmountsAsStrings,
+	}
+}
+
+// amountFormat returns the amount formatting setupRouter put in the context.
+func amountFormat(c *gin.Context) amountFormatting {
+	return c.MustGet("amounts").(amountFormatting)
+}
+
+// pow10 returns 10^n for n <= 19.
+func pow10(n int) uint64 {
+	result := uint64(1)
+	for ; n > 0; n-- {
+		result *= 10
+	}
+	return result
+}
+
+// tokens converts micro-units to whole tokens for values computed as floats,
+// such as USD values.
+func (f amountFormatting) tokens(micro float64) float64 {
+	return micro / float64(pow10(f.tokenDecimals))
+}
+
+// micro converts whole tokens, such as a query param, to micro-units.
+func (f amountFormatting) micro(tokens float64) float64 {
+	return tokens * float64(pow10(f.tokenDecimals))
+}
+
+// format renders micro in whole tokens with f.decimals places, rounding
+// half away from zero.
+func (f amountFormatting) format(micro int64) string {
+	sign := ""
+	units := uint64(micro)
+	if micro < 0 {
+		sign = "-"
+		units = uint64(-(micro + 1)) + 1 // avoids overflowing on MinInt64
+	}
+
+	scale := pow10(f.tokenDecimals)
+	whole, frac := units/scale, units%scale
+	if drop := pow10(f.tokenDecimals - f.decimals); drop > 1 {
+		frac = (frac + drop/2) / drop
+		if frac == pow10(f.decimals) {
+			whole, frac = whole+1, 0
+		}
+	}
+	if whole == 0 && frac == 0 {
+		sign = ""
+	}
+
+	if f.decimals == 0 {
+		return sign + strconv.FormatUint(whole, 10)
+	}
+	digits := strconv.FormatUint(frac, 10)
+	return sign + strconv.FormatUint(whole, 10) + "." + strings.Repeat("0", f.decimals-len(digits)) + digits
+}
+
+// tokenAmount is an earnings amount in micro-units rendered in whole tokens,
+// e.g. 1500000 as 1.500000. It is written as an exact decimal with the
+// configured number of places rather than going through float64, which loses
+// precision and switches to exponent notation for large amounts.
+type tokenAmount struct {
+	micro  int64
+	format amountFormatting
+}
+
+// amount returns micro as a tokenAmount rendered with f.
+func (f amountFormatting) amount(micro int64) tokenAmount {
+	return tokenAmount{micro: micro, format: f}
+}
+
+func (a tokenAmount) MarshalJSON() ([]byte, error) {
+	formatted := a.format.format(a.micro)
+	if a.format.asString {
+		return []byte(strconv.Quote(formatted)), nil
+	}
+	return []byte(formatted), nil
+}
+
+// tokenSymbol returns the token symbol of the configured network, reported
+// alongside token amounts.
+func tokenSymbol(c *gin.Context) string {
+	return c.MustGet("config").(*config.Config).TokenSymbol()
+}
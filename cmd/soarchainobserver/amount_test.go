@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestAmountFormattingFormat(t *testing.T) {
+	tests := []struct {
+		format amountFormatting
+		micro  int64
+		want   string
+	}{
+		{amountFormatting{tokenDecimals: 6, decimals: 6}, 1500000, "1.500000"},
+		{amountFormatting{tokenDecimals: 6, decimals: 6}, 1, "0.000001"},
+		{amountFormatting{tokenDecimals: 6, decimals: 2}, 1994999, "1.99"},
+		{amountFormatting{tokenDecimals: 6, decimals: 2}, 1995000, "2.00"}, // half away from zero
+		{amountFormatting{tokenDecimals: 6, decimals: 2}, -1995000, "-2.00"},
+		{amountFormatting{tokenDecimals: 6, decimals: 2}, -4000, "0.00"}, // no negative zero
+		{amountFormatting{tokenDecimals: 6, decimals: 0}, 2500000, "3"},
+		{amountFormatting{tokenDecimals: 0, decimals: 0}, 42, "42"},
+		{amountFormatting{tokenDecimals: 6, decimals: 6}, math.MinInt64, "-9223372036854.775808"},
+		{amountFormatting{tokenDecimals: 6, decimals: 6}, math.MaxInt64, "9223372036854.775807"},
+	}
+	for _, tt := range tests {
+		if got := tt.format.format(tt.micro); got != tt.want {
+			t.Errorf("%+v.format(%d) = %s, want %s", tt.format, tt.micro, got, tt.want)
+		}
+	}
+}
+
+func TestTokenAmountMarshalJSON(t *testing.T) {
+	number := amountFormatting{tokenDecimals: 6, decimals: 6}
+	str := amountFormatting{tokenDecimals: 6, decimals: 6, asString: true}
+
+	got, err := json.Marshal(map[string]tokenAmount{"a": number.amount(1500000), "b": str.amount(1500000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":1.500000,"b":"1.500000"}`; string(got) != want {
+		t.Errorf("marshaled %s, want %s", got, want)
+	}
+}
+
+// Routers configured differently must not affect each other's responses.
+func TestAmountFormattingIsPerRouter(t *testing.T) {
+	plainCfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, plainCfg)
+	plain := newTestRouter(t, db, plainCfg)
+	stringsCfg := loadTestConfig(t, `{"amount_decimals": 2, "amounts_as_strings": true}`)
+	quoted := newTestRouter(t, db, stringsCfg)
+
+	if err := db.Create(&models.Client{Address: "soar1a", SolanaAddress: testWallet,
+		TotalLifetimeEarnings: 1234567, LastChallengeTime: time.Now().UTC()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/api/v1/miner/dashboard?wallet=" + testWallet
+	for _, run := range []struct {
+		name   string
+		router http.Handler
+		want   string
+	}{
+		{"plain", plain, `"totalLifetimeEarnings":1.234567`},
+		{"quoted", quoted, `"totalLifetimeEarnings":"1.23"`},
+		{"plain again", plain, `"totalLifetimeEarnings":1.234567`},
+	} {
+		if body := serve(run.router, http.MethodGet, path, "").Body.String(); !strings.Contains(body, run.want) {
+			t.Errorf("%s router: %s does not contain %s", run.name, body, run.want)
+		}
+	}
+
+	// minAmount is read in whole tokens with the configured decimals
+	c := paramContext("/?minAmount=1.5", loadTestConfig(t, `{"token_decimals": 3}`))
+	if got, err := parseMinAmount(c); err != nil || got != 1500 {
+		t.Errorf("parseMinAmount = %d, %v; want 1500", got, err)
+	}
+}
+
+// TestLargeAmountsRoundTrip sends amounts above 2^53, which a float64 can't
+// hold exactly, through the API and parses them back.
+func TestLargeAmountsRoundTrip(t *testing.T) {
+	const big = int64(1)<<53 + 1 // 9007199254740993
+	got, err := json.Marshal(microAmount(big))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `"9007199254740993"` {
+		t.Errorf("microAmount marshaled as %s", got)
+	}
+	if got := (amountFormatting{tokenDecimals: 6, decimals: 6}).format(big); got != "9007199254.740993" {
+		t.Errorf("tokenAmount formatted as %s", got)
+	}
+
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+	now := time.Now().UTC()
+	if err := db.Create(&models.Client{Address: "soar1whale", SolanaAddress: testWallet,
+		TotalLifetimeEarnings: big, LastChallengeTime: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.ClientEarning{ClientAddress: testWallet, CoreAddress: "soar1whale",
+		Earnings: big, Timestamp: now.Add(-time.Minute)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(router, http.MethodGet, "/api/v1/client/soar1whale?period=1h", "")
+	var body struct {
+		TotalLifetimeEarnings string
+		EarningsOverPeriod    string
+	}
+	decodeJSONInto(t, w, &body)
+	for name, raw := range map[string]string{
+		"totalLifetimeEarnings": body.TotalLifetimeEarnings,
+		"earningsOverPeriod":    body.EarningsOverPeriod,
+	} {
+		if parsed, err := strconv.ParseInt(raw, 10, 64); err != nil || parsed != big {
+			t.Errorf("%s = %q, want %d", name, raw, big)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// archiveInterval is how often old epochs are moved to epoch_archive
+const archiveInterval = time.Hour
+
+// archiveEpochsQuery moves epoch_earnings rows that ended before the cutoff
+// into epoch_archive in a single statement, so an epoch is never visible in
+// both tables or in neither. Rows for an already archived epoch (e.g. from
+// a late backfill) are added to the archived total.
+const archiveEpochsQuery = `
+        WITH moved AS (
+            DELETE FROM epoch_earnings
+            WHERE end_time < ?
+            RETURNING client_address, identifier, epoch_number, start_time, end_time, total_earnings
+        )
+        INSERT INTO epoch_archive (client_address, identifier, epoch_number, start_time, end_time, total_earnings)
+        SELECT client_address, identifier, epoch_number, start_time, end_time, total_earnings
+        FROM moved
+        ON CONFLICT (client_address, identifier, epoch_number, start_time)
+        DO UPDATE SET total_earnings = epoch_archive.total_earnings + EXCLUDED.total_earnings
+    `
+
+// epochRewardsUnion combines live and archived epochs with the columns the
+// reward endpoints read. An epoch can be in both tables until the next
+// archive run, when a backfill adds earnings to an already archived epoch,
+// so rows of the same epoch are merged the way that run will merge them.
+const epochRewardsUnion = `
+        SELECT client_address, identifier, epoch_number, start_time,
+            MAX(end_time) AS end_time, SUM(total_earnings) AS total_earnings
+        FROM (
+            SELECT client_address, identifier, epoch_number, start_time, end_time, total_earnings
+            FROM epoch_earnings
+            UNION ALL
+            SELECT client_address, identifier, epoch_number, start_time, end_time, total_earnings
+            FROM epoch_archive
+        ) AS epochs
+        GROUP BY client_address, identifier, epoch_number, start_time
+    `
+
+// epochRewards returns a query over live and archived epochs that can be
+// filtered and scanned into models.EpochEarnings like the live table.
+func epochRewards(db *gorm.DB) *gorm.DB {
+	return db.Table("(" + epochRewardsUnion + ") AS epoch_earnings")
+}
+
+// archiveEpochs moves epochs that ended more than archiveAfter ago into
+// epoch_archive, once right away and then every archiveInterval until ctx
+// is cancelled.
+func archiveEpochs(ctx context.Context, db *gorm.DB, archiveAfter time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().UTC().Add(-archiveAfter)
+		result := db.WithContext(ctx).Exec(archiveEpochsQuery, cutoff)
+		if result.Error != nil && ctx.Err() == nil {
+			logger.Printf("Error archiving epochs: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			logger.Printf("Archived %d epoch rows that ended before %s", result.RowsAffected, cutoff.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestAllRewardsIncludeArchivedEpochs(t *testing.T) {
+	cfg := loadTestConfig(t, `{"token_decimals": 6}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	day := func(n int) time.Time { return time.Date(2025, 1, n, 0, 0, 0, 0, time.UTC) }
+	archived := []models.EpochArchive{
+		{ClientAddress: testWallet, Identifier: "day", EpochNumber: 1, StartTime: day(1), EndTime: day(2), TotalEarnings: 1000000},
+		{ClientAddress: testWallet, Identifier: "day", EpochNumber: 2, StartTime: day(2), EndTime: day(3), TotalEarnings: 2000000},
+	}
+	live := []models.EpochEarnings{
+		// a late backfill of the archived epoch 2, not archived yet
+		{ClientAddress: testWallet, Identifier: "day", EpochNumber: 2, StartTime: day(2), EndTime: day(3), TotalEarnings: 500000},
+		{ClientAddress: testWallet, Identifier: "day", EpochNumber: 3, StartTime: day(3), EndTime: day(4), TotalEarnings: 3000000},
+	}
+	if err := db.Create(&archived).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&live).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var rewards []map[string]interface{}
+	w := serve(router, http.MethodGet, "/api/v1/miner/all-rewards?wallet="+testWallet, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	decodeJSONInto(t, w, &rewards)
+
+	want := []struct {
+		epoch    float64
+		earnings float64
+		endTime  string
+	}{
+		{1, 1, "2025-01-02T00:00:00Z"},
+		{2, 2.5, "2025-01-03T00:00:00Z"},
+		{3, 3, "2025-01-04T00:00:00Z"},
+	}
+	if len(rewards) != len(want) {
+		t.Fatalf("got %d epochs, want %d: %v", len(rewards), len(want), rewards)
+	}
+	for i, reward := range rewards {
+		if reward["epochNumber"] != want[i].epoch || reward["totalEarnings"] != want[i].earnings || reward["endTime"] != want[i].endTime {
+			t.Errorf("reward %d = %v, want epoch %v earning %v ending %s", i, reward, want[i].epoch, want[i].earnings, want[i].endTime)
+		}
+	}
+
+	body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miner/epoch?wallet="+testWallet+"&epoch=2", ""), http.StatusOK)
+	if body["totalEarnings"] != 2.5 {
+		t.Errorf("epoch 2 earned %v, want 2.5", body["totalEarnings"])
+	}
+}
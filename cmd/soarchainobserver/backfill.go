@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"gorm.io/gorm"
+)
+
+// runBackfill parses the backfill subcommand flags and replays the requested
+// epoch range from the chain.
+func runBackfill(cfg *config.Config, db *gorm.DB, args []string, logger *log.Logger) error {
+	flags := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	fromEpoch := flags.Int64("from-epoch", -1, "first epoch to backfill (inclusive)")
+	toEpoch := flags.Int64("to-epoch", -1, "last epoch to backfill (inclusive)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *fromEpoch < 0 || *toEpoch < 0 {
+		flags.Usage()
+		return errors.New("--from-epoch and --to-epoch are required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Printf("Backfilling epochs %d to %d", *fromEpoch, *toEpoch)
+	if err := blockchain.Backfill(ctx, cfg, db, *fromEpoch, *toEpoch, logger); err != nil {
+		return err
+	}
+	logger.Println("Backfill complete")
+	return nil
+}
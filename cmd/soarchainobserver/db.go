@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/utils"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// openDatabase connects to Postgres using the DB_* environment variables,
+// configures the connection pool and migrates the schema.
+func openDatabase(cfg *config.Config) (*gorm.DB, error) {
+	return openDatabaseWith(postgresDialector(), cfg)
+}
+
+// postgresDialector returns the production dialector, configured from the
+// DB_* environment variables.
+func postgresDialector() gorm.Dialector {
+	// Read database credentials from env
+	dbHost := os.Getenv("DB_HOST")
+	dbPort := os.Getenv("DB_PORT")
+	dbUser := os.Getenv("DB_USER")
+	dbPassword := os.Getenv("DB_PASSWORD")
+	dbName := os.Getenv("DB_NAME")
+
+	dsn := fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		dbHost, dbUser, dbPassword, dbName, dbPort,
+	)
+	return postgres.Open(dsn)
+}
+
+// openDatabaseWith opens the database behind dialector, configures the
+// connection pool and migrates the schema. Tests can pass another dialector
+// such as sqlite; the Postgres-only data backfills and schema fixes are
+// skipped for those.
+func openDatabaseWith(dialector gorm.Dialector, cfg *config.Config) (*gorm.DB, error) {
+	// Initialize database connection
+	// PrepareStmt caches a prepared statement per distinct SQL string, so the
+	// hot per-wallet queries are only parsed and planned once per connection.
+	// Variable parts go through bind parameters, which keeps the cache bounded.
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:      utils.NewGormLogger(utils.GetStructuredLogger(), cfg.SlowQueryThreshold.Duration),
+		PrepareStmt: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Set up DB connection pooling
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database handle: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(25)
+	sqlDB.SetMaxIdleConns(25)
+	sqlDB.SetConnMaxLifetime(5 * time.Minute)
+
+	// Migrate the schema. The clients table goes last, so addClientHistory
+	// reads an up to date earnings table.
+	if err := db.AutoMigrate(
+		&models.ClientEarning{},
+		&models.EpochEarnings{},
+		&models.EpochArchive{},
+		&models.ProcessedTx{},
+		&models.StatusTransition{},
+		&models.PriceHistory{},
+	); err != nil {
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+	if err := addClientHistory(db); err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&models.Client{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate database schema: %w", err)
+	}
+
+	if db.Dialector.Name() != "postgres" {
+		return db, nil
+	}
+
+	// Drop indexes superseded by the composite per-wallet indexes
+	for _, obsolete := range []struct {
+		model interface{}
+		index string
+	}{
+		{&models.ClientEarning{}, "idx_client_earnings_client_address"},
+		{&models.EpochEarnings{}, "idx_epoch_earnings_client_address"},
+		{&models.EpochEarnings{}, "idx_epoch_earnings_address_epoch"},
+	} {
+		if db.Migrator().HasIndex(obsolete.model, obsolete.index) {
+			if err := db.Migrator().DropIndex(obsolete.model, obsolete.index); err != nil {
+				return nil, fmt.Errorf("failed to drop index %s: %w", obsolete.index, err)
+			}
+		}
+	}
+
+	// AutoMigrate doesn't change existing primary keys; widen the one of
+	// epoch_archive to include the epoch start time
+	var archiveKeyColumns int64
+	if err := db.Raw(`
+        SELECT COUNT(*) FROM information_schema.key_column_usage
+        WHERE table_name = 'epoch_archive' AND constraint_name = 'epoch_archive_pkey'
+    `).Scan(&archiveKeyColumns).Error; err != nil {
+		return nil, fmt.Errorf("failed to inspect epoch_archive primary key: %w", err)
+	}
+	if archiveKeyColumns == 3 {
+		if err := db.Exec(`
+            ALTER TABLE epoch_archive DROP CONSTRAINT epoch_archive_pkey,
+            ADD PRIMARY KEY (client_address, identifier, epoch_number, start_time)
+        `).Error; err != nil {
+			return nil, fmt.Errorf("failed to widen epoch_archive primary key: %w", err)
+		}
+	}
+
+	return db, nil
+}
+
+// addClientHistory adds the first-seen time and challenge count columns to
+// a clients table created before they were tracked, and fills them in from
+// the earnings rows still stored. Rows from before core addresses were
+// tracked are matched by the earnings address. Columns and backfill are
+// added in one transaction, so it runs exactly once: later startups find
+// the columns in place.
+func addClientHistory(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Client{}) || db.Migrator().HasColumn(&models.Client{}, "ChallengeCount") {
+		return nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, field := range []string{"CreatedAt", "ChallengeCount"} {
+			if !tx.Migrator().HasColumn(&models.Client{}, field) {
+				if err := tx.Migrator().AddColumn(&models.Client{}, field); err != nil {
+					return err
+				}
+			}
+		}
+		// Clients without earnings rows left were first seen at their last
+		// challenge as far as we know
+		if err := tx.Exec(`UPDATE clients SET created_at = last_challenge_time`).Error; err != nil {
+			return err
+		}
+		// The earnings are aggregated per client once, rather than
+		// searched for each client
+		return tx.Exec(`
+            UPDATE clients
+            SET created_at = history.first_seen, challenge_count = history.challenges
+            FROM (
+                SELECT address, MIN(first_seen) AS first_seen, SUM(challenges) AS challenges
+                FROM (
+                    SELECT core_address AS address, MIN(timestamp) AS first_seen, COUNT(*) AS challenges
+                    FROM client_earnings
+                    WHERE core_address <> ''
+                    GROUP BY core_address
+                    UNION ALL
+                    SELECT c.address, MIN(ce.timestamp), COUNT(*)
+                    FROM client_earnings ce
+                    JOIN clients c ON ce.client_address = COALESCE(NULLIF(c.solana_address, ''), c.address)
+                    WHERE ce.core_address = ''
+                    GROUP BY c.address
+                ) per_source
+                GROUP BY address
+            ) history
+            WHERE history.address = clients.address
+        `).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to backfill client first-seen times and challenge counts: %w", err)
+	}
+	return nil
+}
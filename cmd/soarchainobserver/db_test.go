@@ -0,0 +1,140 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// reopener returns a function opening the sqlite database at dsn through
+// openDatabaseWith.
+func reopener(t *testing.T, dsn string) func() *gorm.DB {
+	cfg := loadTestConfig(t, `{}`)
+	return func() *gorm.DB {
+		t.Helper()
+		db, err := openDatabaseWith(testDialector(dsn), cfg)
+		if err != nil {
+			t.Fatalf("openDatabaseWith: %v", err)
+		}
+		t.Cleanup(func() {
+			if stmtDB, ok := db.ConnPool.(*gorm.PreparedStmtDB); ok {
+				stmtDB.Close()
+			}
+			if sqlDB, err := db.DB(); err == nil {
+				sqlDB.Close()
+			}
+		})
+		return db
+	}
+}
+
+func TestOpenDatabaseWithSQLite(t *testing.T) {
+	open := reopener(t, "file:"+filepath.Join(t.TempDir(), "observer.db"))
+
+	db := open()
+	for _, model := range []interface{}{
+		&models.Client{},
+		&models.ClientEarning{},
+		&models.EpochEarnings{},
+		&models.EpochArchive{},
+		&models.ProcessedTx{},
+		&models.StatusTransition{},
+		&models.PriceHistory{},
+	} {
+		if !db.Migrator().HasTable(model) {
+			t.Errorf("table of %T not created", model)
+		}
+	}
+	if err := db.Create(&models.Client{Address: "soar1a", ChallengeCount: 3}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Opening an existing database migrates it again without losing rows
+	db = open()
+	var client models.Client
+	if err := db.First(&client, "address = ?", "soar1a").Error; err != nil {
+		t.Fatalf("client lost on reopening: %v", err)
+	}
+	if client.ChallengeCount != 3 {
+		t.Errorf("challenge count = %d after reopening, want 3", client.ChallengeCount)
+	}
+}
+
+func TestAddClientHistoryRunsOnce(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "observer.db")
+
+	// A database from before first-seen times and challenge counts were
+	// tracked
+	legacy, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := legacy.Exec(`CREATE TABLE clients (
+        address TEXT PRIMARY KEY, pub_key TEXT, solana_address TEXT,
+        total_lifetime_earnings INTEGER, last_challenge_time DATETIME, deleted_at DATETIME)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := legacy.AutoMigrate(&models.ClientEarning{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, client := range []struct {
+		address, solana string
+		last            time.Time
+	}{
+		{"soar1bound", "SoLwallet", base.Add(48 * time.Hour)},
+		{"soar1unbound", "", base.Add(24 * time.Hour)},
+		{"soar1idle", "", base.Add(72 * time.Hour)},
+	} {
+		if err := legacy.Exec(`INSERT INTO clients (address, pub_key, solana_address, total_lifetime_earnings, last_challenge_time) VALUES (?, 'pk', ?, 0, ?)`,
+			client.address, client.solana, client.last).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := legacy.Create(&[]models.ClientEarning{
+		// attributed rows
+		{ClientAddress: "SoLwallet", CoreAddress: "soar1bound", Earnings: 1, Timestamp: base.Add(2 * time.Hour)},
+		{ClientAddress: "SoLwallet", CoreAddress: "soar1bound", Earnings: 1, Timestamp: base.Add(3 * time.Hour)},
+		// rows from before core addresses were tracked, matched by the
+		// earnings address
+		{ClientAddress: "SoLwallet", Earnings: 1, Timestamp: base.Add(time.Hour)},
+		{ClientAddress: "soar1unbound", Earnings: 1, Timestamp: base.Add(5 * time.Hour)},
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := legacy.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	open := reopener(t, dsn)
+	check := func(db *gorm.DB, address string, wantFirstSeen time.Time, wantCount int64) {
+		t.Helper()
+		var client models.Client
+		if err := db.First(&client, "address = ?", address).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !client.CreatedAt.Equal(wantFirstSeen) || client.ChallengeCount != wantCount {
+			t.Errorf("%s: first seen %s with %d challenges, want %s with %d",
+				address, client.CreatedAt, client.ChallengeCount, wantFirstSeen, wantCount)
+		}
+	}
+
+	db := open()
+	check(db, "soar1bound", base.Add(time.Hour), 3)
+	check(db, "soar1unbound", base.Add(5*time.Hour), 1)
+	check(db, "soar1idle", base.Add(72*time.Hour), 0)
+
+	// Later startups leave the counts maintained since alone
+	if err := db.Model(&models.Client{}).Where("address = ?", "soar1idle").Update("challenge_count", 0).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.ClientEarning{ClientAddress: "soar1idle", Earnings: 1, Timestamp: base}).Error; err != nil {
+		t.Fatal(err)
+	}
+	db = open()
+	check(db, "soar1idle", base.Add(72*time.Hour), 0)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// getPubKeyEarnings handles GET /api/v1/pubkey/:pubkey/earnings?period=7d
+// Miners may rotate solana addresses while keeping the same pubkey, so this
+// sums the earnings of every client registered under the pubkey.
+func getPubKeyEarnings(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	pubkey := c.Param("pubkey")
+
+	window, err := parsePeriodWindow(c, "1h")
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	includeInactive, err := parseIncludeInactive(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+	scoped := db
+	if includeInactive {
+		scoped = db.Unscoped()
+	}
+
+	var clients []models.Client
+	if err := scoped.Where("pub_key = ?", pubkey).Find(&clients).Error; err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+	if len(clients) == 0 {
+		respondError(c, http.StatusNotFound, "Client not found")
+		return
+	}
+
+	// Earnings rows record the core address that earned them, so cores of
+	// other pubkeys paying out to the same solana address aren't counted.
+	// Legacy rows without a core address fall back to the earnings address
+	// (the solana address, or the core address until one is known).
+	coreAddresses := make([]string, 0, len(clients))
+	earningsAddresses := make([]string, 0, len(clients))
+	for i := range clients {
+		coreAddresses = append(coreAddresses, clients[i].Address)
+		earningsAddresses = append(earningsAddresses, clients[i].EarningsAddress())
+	}
+	var totalEarnings int64
+	err = db.Model(&models.ClientEarning{}).
+		Where("(core_address IN ? OR (COALESCE(core_address, '') = '' AND client_address IN ?)) AND timestamp BETWEEN ? AND ?",
+			coreAddresses, earningsAddresses, window.Start, window.End).
+		Select("COALESCE(SUM(earnings), 0)").
+		Scan(&totalEarnings).Error
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	addresses := make([]gin.H, 0, len(clients))
+	for _, client := range clients {
+		addresses = append(addresses, gin.H{
+			"address":       client.Address,
+			"solanaAddress": client.SolanaAddress,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pubkey":        pubkey,
+		"addresses":     addresses,
+		"totalEarnings": amountFormat(c).amount(totalEarnings),
+		"tokenSymbol":   tokenSymbol(c),
+		"period":        window.Period,
+		"align":         window.Align,
+		"startTime":     window.Start.Format(time.RFC3339),
+		"endTime":       window.End.Format(time.RFC3339),
+	})
+}
+
+// getSolanaAggregate handles GET /api/v1/solana/:solanaAddress/aggregate?period=7d
+// Operators may run several cores paying out to one solana address; this
+// returns each client's earnings over the period and their combined total.
+// Earnings recorded before rows carried the earning client are reported as
+// unattributedEarnings and only count towards the total.
+func getSolanaAggregate(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	solanaAddress := c.Param("solanaAddress")
+	if !solanaAddressPattern.MatchString(solanaAddress) {
+		respondError(c, http.StatusBadRequest, "Invalid solana address")
+		return
+	}
+
+	window, err := parsePeriodWindow(c, "7d")
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	includeInactive, err := parseIncludeInactive(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+	scoped := db
+	if includeInactive {
+		scoped = db.Unscoped()
+	}
+
+	var clients []models.Client
+	if err := scoped.Where("solana_address = ?", solanaAddress).Order("address").Find(&clients).Error; err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+	if len(clients) == 0 {
+		respondError(c, http.StatusNotFound, "Client not found")
+		return
+	}
+
+	// Rows are keyed by the solana address, or by the core address of a
+	// client whose earnings haven't been moved to it yet
+	keys := []string{solanaAddress}
+	for _, client := range clients {
+		keys = append(keys, client.Address)
+	}
+	var rows []struct {
+		ClientAddress string
+		CoreAddress   string
+		Total         int64
+	}
+	err = db.Model(&models.ClientEarning{}).
+		Select("client_address, core_address, SUM(earnings) AS total").
+		Where("client_address IN ? AND timestamp BETWEEN ? AND ?", keys, window.Start, window.End).
+		Group("client_address, core_address").
+		Scan(&rows).Error
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	perAddress := make(map[string]int64, len(rows))
+	var totalEarnings, unattributed int64
+	for _, row := range rows {
+		totalEarnings += row.Total
+		// Rows keyed by a core address were earned by that client
+		earnedBy := row.CoreAddress
+		if earnedBy == "" && row.ClientAddress != solanaAddress {
+			earnedBy = row.ClientAddress
+		}
+		if earnedBy == "" {
+			unattributed += row.Total
+			continue
+		}
+		perAddress[earnedBy] += row.Total
+	}
+
+	addresses := make([]gin.H, 0, len(clients))
+	for _, client := range clients {
+		addresses = append(addresses, gin.H{
+			"address":       client.Address,
+			"pubkey":        client.PubKey,
+			"totalEarnings": amountFormat(c).amount(perAddress[client.Address]),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"solanaAddress":        solanaAddress,
+		"addresses":            addresses,
+		"unattributedEarnings": amountFormat(c).amount(unattributed),
+		"totalEarnings":        amountFormat(c).amount(totalEarnings),
+		"tokenSymbol":          tokenSymbol(c),
+		"period":               window.Period,
+		"align":                window.Align,
+		"startTime":            window.Start.Format(time.RFC3339),
+		"endTime":              window.End.Format(time.RFC3339),
+	})
+}
+
+// getMinerEarnings handles GET /api/v1/miner/earnings?wallet=&from=&to=&page=&pageSize=
+// It lists the wallet's individual earnings rows, newest first, optionally
+// limited to a time range.
+func getMinerEarnings(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+	timestamps, err := parseTimeRange(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+	page, err := parsePagination(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	// A new session so the query can be reused for the count and the page
+	query := timestamps.apply(db.Model(&models.ClientEarning{}).Where("client_address = ?", wallet)).
+		Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	var rows []models.ClientEarning
+	err = page.apply(query.Order("timestamp DESC, id DESC")).Find(&rows).Error
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	earnings := make([]gin.H, 0, len(rows))
+	for _, row := range rows {
+		earnings = append(earnings, gin.H{
+			"timestamp":   row.Timestamp.Format(time.RFC3339),
+			"amount":      amountFormat(c).amount(row.Earnings),
+			"coreAddress": nilIfEmpty(row.CoreAddress),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"wallet":      wallet,
+		"page":        page.Page,
+		"pageSize":    page.PageSize,
+		"total":       total,
+		"earnings":    earnings,
+		"tokenSymbol": tokenSymbol(c),
+	})
+}
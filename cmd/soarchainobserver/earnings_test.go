@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestPubKeyEarningsIncludesClientsWithoutSolanaAddress(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	now := time.Now().UTC()
+	// One client is bound to a solana address, the other still has its
+	// earnings stored under its core address
+	clients := []models.Client{
+		{Address: "soar1bound", PubKey: "pk", SolanaAddress: "SoLwallet", LastChallengeTime: now},
+		{Address: "soar1unbound", PubKey: "pk", LastChallengeTime: now},
+		{Address: "soar1other", PubKey: "other", LastChallengeTime: now},
+		// a core of another pubkey paying out to the same solana address
+		{Address: "soar1shared", PubKey: "shared", SolanaAddress: "SoLwallet", LastChallengeTime: now},
+	}
+	earnings := []models.ClientEarning{
+		{ClientAddress: "SoLwallet", CoreAddress: "soar1bound", Earnings: 1_000_000, Timestamp: now.Add(-10 * time.Minute)},
+		{ClientAddress: "soar1unbound", CoreAddress: "soar1unbound", Earnings: 500_000, Timestamp: now.Add(-5 * time.Minute)},
+		{ClientAddress: "soar1other", CoreAddress: "soar1other", Earnings: 7_000_000, Timestamp: now.Add(-5 * time.Minute)},
+		{ClientAddress: "SoLwallet", CoreAddress: "soar1shared", Earnings: 3_000_000, Timestamp: now.Add(-5 * time.Minute)},
+		// recorded before core addresses were tracked, counted by the wallet
+		{ClientAddress: "SoLwallet", Earnings: 250_000, Timestamp: now.Add(-15 * time.Minute)},
+	}
+	if err := db.Create(&clients).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&earnings).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/pubkey/pk/earnings?period=1h", ""), http.StatusOK)
+	if got := body["totalEarnings"]; got != 1.75 {
+		t.Errorf("totalEarnings = %v, want 1.75 without the shared wallet's other core", got)
+	}
+	if addresses := body["addresses"].([]interface{}); len(addresses) != 2 {
+		t.Errorf("addresses = %v, want the 2 clients under the pubkey", addresses)
+	}
+}
+
+func TestSolanaAggregate(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	now := time.Now().UTC()
+	clients := []models.Client{
+		{Address: "soar1alice", PubKey: "pkalice", SolanaAddress: testWallet, LastChallengeTime: now},
+		{Address: "soar1bob", PubKey: "pkbob", SolanaAddress: testWallet, LastChallengeTime: now},
+	}
+	earnings := []models.ClientEarning{
+		{ClientAddress: testWallet, CoreAddress: "soar1alice", Earnings: 1_000_000, Timestamp: now.Add(-30 * time.Minute)},
+		{ClientAddress: testWallet, CoreAddress: "soar1alice", Earnings: 500_000, Timestamp: now.Add(-20 * time.Minute)},
+		// stored under bob's core address before the solana address was known
+		{ClientAddress: "soar1bob", Earnings: 2_000_000, Timestamp: now.Add(-10 * time.Minute)},
+		// from before core addresses were recorded
+		{ClientAddress: testWallet, Earnings: 250_000, Timestamp: now.Add(-5 * time.Minute)},
+		// outside the period
+		{ClientAddress: testWallet, CoreAddress: "soar1alice", Earnings: 9_000_000, Timestamp: now.Add(-2 * time.Hour)},
+	}
+	if err := db.Create(&clients).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&earnings).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/solana/"+testWallet+"/aggregate?period=1h", ""), http.StatusOK)
+	if body["totalEarnings"] != 3.75 || body["unattributedEarnings"] != 0.25 {
+		t.Errorf("total %v, unattributed %v; want 3.75 and 0.25", body["totalEarnings"], body["unattributedEarnings"])
+	}
+	want := map[string]float64{"soar1alice": 1.5, "soar1bob": 2}
+	addresses := body["addresses"].([]interface{})
+	if len(addresses) != len(want) {
+		t.Fatalf("addresses = %v, want %v", addresses, want)
+	}
+	for _, entry := range addresses {
+		address := entry.(map[string]interface{})
+		if address["totalEarnings"] != want[address["address"].(string)] {
+			t.Errorf("%v earned %v, want %v", address["address"], address["totalEarnings"], want[address["address"].(string)])
+		}
+	}
+}
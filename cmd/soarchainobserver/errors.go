@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dbPingTimeout bounds the connectivity check of respondDBError
+const dbPingTimeout = 2 * time.Second
+
+// respondError aborts the request with a JSON error body. Every error
+// response carries the request ID so a failing call can be matched to its
+// log lines.
+func respondError(c *gin.Context, status int, message string) {
+	respondErrorWith(c, status, message, nil)
+}
+
+// respondErrorWith is respondError with additional fields in the body.
+func respondErrorWith(c *gin.Context, status int, message string, fields gin.H) {
+	body := gin.H{}
+	for k, v := range fields {
+		body[k] = v
+	}
+	body["error"] = message
+	body["requestId"] = c.GetString(requestIDKey)
+	c.AbortWithStatusJSON(status, body)
+}
+
+// invalidParamError is a well-formed query param whose value makes no sense,
+// such as an inverted range. It is answered with 422 and a code clients can
+// match on, unlike unparseable params which get 400.
+type invalidParamError struct {
+	Code    string
+	Message string
+}
+
+func (e *invalidParamError) Error() string {
+	return e.Message
+}
+
+// errEpochUnavailable is returned by params resolved from the current epoch
+// while the epoch info hasn't been fetched
+var errEpochUnavailable = errors.New("Epoch info unavailable")
+
+// respondParamError answers a query param error: 422 with its code for an
+// invalidParamError, 503 when it needs epoch info that isn't available and
+// 400 otherwise.
+func respondParamError(c *gin.Context, err error) {
+	if errors.Is(err, errEpochUnavailable) {
+		respondError(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	var invalid *invalidParamError
+	if errors.As(err, &invalid) {
+		respondErrorWith(c, http.StatusUnprocessableEntity, invalid.Message, gin.H{"code": invalid.Code})
+		return
+	}
+	respondError(c, http.StatusBadRequest, err.Error())
+}
+
+// respondDBError answers a failed database call without exposing the
+// underlying error, which only goes to the request log. It answers 503 when
+// the database can't be reached and 500 otherwise.
+func respondDBError(c *gin.Context, db *gorm.DB, err error) {
+	respondDBErrorWith(c, db, err, nil)
+}
+
+// respondDBErrorWith is respondDBError with additional fields in the body.
+func respondDBErrorWith(c *gin.Context, db *gorm.DB, err error, fields gin.H) {
+	c.Error(err)
+	if !dbReachable(db) {
+		respondErrorWith(c, http.StatusServiceUnavailable, "Database unavailable", fields)
+		return
+	}
+	respondErrorWith(c, http.StatusInternalServerError, "Internal server error", fields)
+}
+
+// partialWarnings collects the parts of a composite response that couldn't
+// be computed. The response is still sent with the other parts, naming the
+// missing ones under "warnings"; the errors go to the request log.
+type partialWarnings []string
+
+// add records that field couldn't be computed because of err.
+func (w *partialWarnings) add(c *gin.Context, field string, err error) {
+	c.Error(err)
+	*w = append(*w, field)
+}
+
+// list returns the warnings, as an empty list rather than null when there
+// are none.
+func (w partialWarnings) list() []string {
+	if w == nil {
+		return []string{}
+	}
+	return w
+}
+
+// dbReachable pings the database to tell connection failures apart from
+// query errors.
+func dbReachable(db *gorm.DB) bool {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dbPingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx) == nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestDatabaseErrorsAreMapped(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	const path = "/api/v1/miners?status=down"
+
+	t.Run("query error", func(t *testing.T) {
+		db := openTestDB(t, cfg)
+		router := newTestRouter(t, db, cfg)
+		if err := db.Migrator().DropTable(&models.Client{}); err != nil {
+			t.Fatal(err)
+		}
+		w := serve(router, http.MethodGet, path, "")
+		body := decodeJSON(t, w, http.StatusInternalServerError)
+		if body["error"] != "Internal server error" || strings.Contains(w.Body.String(), "clients") {
+			t.Errorf("body %s exposes the query error", w.Body)
+		}
+	})
+
+	t.Run("closed database", func(t *testing.T) {
+		db := openTestDB(t, cfg)
+		router := newTestRouter(t, db, cfg)
+		sqlDB, err := db.DB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlDB.Close()
+		w := serve(router, http.MethodGet, path, "")
+		body := decodeJSON(t, w, http.StatusServiceUnavailable)
+		if body["error"] != "Database unavailable" || strings.Contains(w.Body.String(), "closed") {
+			t.Errorf("body %s exposes the connection error", w.Body)
+		}
+	})
+}
+
+// TestSemanticParamErrors checks that well-formed but contradictory params
+// answer 422 with a code, while malformed ones answer 400.
+func TestSemanticParamErrors(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: &blockchain.BlockReader{},
+		Config:      cfg,
+		Logger:      quietLogger,
+		Warmup:      newWarmupState(cfg),
+	})
+
+	wallet := "wallet=" + testWallet
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"/api/v1/miner/all-rewards?" + wallet + "&fromEpoch=5&toEpoch=1", http.StatusUnprocessableEntity, "inverted_epoch_range"},
+		{"/api/v1/miner/all-rewards?" + wallet + "&fromEpoch=five", http.StatusBadRequest, ""},
+		{"/api/v1/miner/earnings?" + wallet + "&from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z", http.StatusUnprocessableEntity, "inverted_time_range"},
+		{"/api/v1/miner/earnings?" + wallet + "&from=yesterday", http.StatusBadRequest, ""},
+		{"/api/v1/miner/latest-rewards?" + wallet + "&minAmount=-1", http.StatusUnprocessableEntity, "negative_amount"},
+		{"/api/v1/miner/latest-rewards?" + wallet + "&minAmount=some", http.StatusBadRequest, ""},
+		{"/api/v1/miner/latest-rewards?" + wallet + "&limit=-1", http.StatusBadRequest, ""},
+		{"/api/v1/miner/latest-rewards?" + wallet + "&limit=abc", http.StatusBadRequest, ""},
+		{"/api/v1/miner/status-history?" + wallet + "&limit=0", http.StatusBadRequest, ""},
+		{"/api/v1/network/leaderboard?limit=ten", http.StatusBadRequest, ""},
+		{"/api/v1/miners?status=up&page=2147483647&pageSize=500", http.StatusUnprocessableEntity, "page_out_of_range"},
+		{"/api/v1/network/epoch-totals?fromEpoch=0&toEpoch=5000", http.StatusUnprocessableEntity, "epoch_range_too_wide"},
+		{"/average?period=epoch&identifier=week", http.StatusUnprocessableEntity, "untracked_identifier"},
+	}
+	for _, tt := range tests {
+		body := decodeJSON(t, serve(router, http.MethodGet, tt.path, ""), tt.wantStatus)
+		if code, _ := body["code"].(string); code != tt.wantCode {
+			t.Errorf("%s: code %q, want %q", tt.path, code, tt.wantCode)
+		}
+		if body["error"] == nil {
+			t.Errorf("%s: no error message", tt.path)
+		}
+	}
+}
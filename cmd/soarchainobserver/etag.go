@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// rewardsETag derives an ETag for a wallet's reward history from the number
+// of epoch rows, the latest epoch, the last update time and the latest
+// recorded price, so it changes as soon as new earnings or prices land. The
+// query string is mixed in because filters and limits change the response
+// body.
+func rewardsETag(db *gorm.DB, c *gin.Context, wallet string) (string, error) {
+	var state struct {
+		RowCount    int64
+		LatestEpoch int64
+		LastUpdate  *time.Time
+	}
+	err := db.Model(&models.EpochEarnings{}).
+		Select("COUNT(*) AS row_count, COALESCE(MAX(epoch_number), 0) AS latest_epoch, MAX(updated_at) AS last_update").
+		Where("client_address = ?", wallet).
+		Scan(&state).Error
+	if err != nil {
+		return "", err
+	}
+
+	var latestPrice int64
+	if err := db.Model(&models.PriceHistory{}).Select("COALESCE(MAX(id), 0)").Scan(&latestPrice).Error; err != nil {
+		return "", err
+	}
+
+	var lastUpdate int64
+	if state.LastUpdate != nil {
+		lastUpdate = state.LastUpdate.UnixNano()
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%d|%d|%s",
+		wallet, state.RowCount, state.LatestEpoch, lastUpdate, latestPrice, c.Request.URL.RawQuery)))
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// notModified sets the ETag header and, when the request's If-None-Match
+// already carries it, answers 304 and returns true.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == etag || candidate == "*" {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/metrics"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// dataAsOfTTL is how long the latest earnings timestamp is reused
+const dataAsOfTTL = 5 * time.Second
+
+// dataFreshness caches the timestamp of the latest stored earning, reported
+// as dataAsOf so consumers can tell zero earnings from lagging ingestion.
+// It is safe for concurrent use.
+type dataFreshness struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	asOf      time.Time
+	expiresAt time.Time
+}
+
+func newDataFreshness(ttl time.Duration) *dataFreshness {
+	return &dataFreshness{ttl: ttl}
+}
+
+// get returns the latest earnings timestamp, zero when there are no
+// earnings, querying the DB at most once per ttl.
+func (df *dataFreshness) get(db *gorm.DB) (time.Time, error) {
+	df.mu.Lock()
+	defer df.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(df.expiresAt) {
+		return df.asOf, nil
+	}
+
+	latest, err := latestEarningTime(db)
+	if err != nil {
+		return time.Time{}, err
+	}
+	df.asOf = latest
+	df.expiresAt = now.Add(df.ttl)
+	return df.asOf, nil
+}
+
+// latestEarningTime returns the timestamp of the latest stored earning,
+// zero when there are no earnings.
+func latestEarningTime(db *gorm.DB) (time.Time, error) {
+	var latest sql.NullTime
+	if err := db.Model(&models.ClientEarning{}).Select("MAX(timestamp)").Row().Scan(&latest); err != nil {
+		return time.Time{}, err
+	}
+	if !latest.Valid {
+		return time.Time{}, nil
+	}
+	return latest.Time.UTC(), nil
+}
+
+// ingestionLag is how far the latest stored earning is behind now. Lag
+// growing while miners are being challenged means the WebSocket stopped
+// delivering or processing stalled. It is false when nothing has been
+// stored yet.
+func ingestionLag(latest, now time.Time) (time.Duration, bool) {
+	if latest.IsZero() {
+		return 0, false
+	}
+	if lag := now.Sub(latest); lag > 0 {
+		return lag, true
+	}
+	return 0, true
+}
+
+// ingestionLagInterval is how often the ingestion_lag_seconds gauge is
+// refreshed
+const ingestionLagInterval = 15 * time.Second
+
+// updateIngestionLagGauge refreshes the ingestion_lag_seconds gauge once
+// right away and then every ingestionLagInterval until ctx is cancelled.
+func updateIngestionLagGauge(ctx context.Context, db *gorm.DB, logger *log.Logger) {
+	ticker := time.NewTicker(ingestionLagInterval)
+	defer ticker.Stop()
+
+	for {
+		latest, err := latestEarningTime(db.WithContext(ctx))
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Printf("Error reading the latest earnings timestamp: %v", err)
+			}
+		} else if lag, ok := ingestionLag(latest, time.Now()); ok {
+			metrics.IngestionLag.Set(lag.Seconds())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dataAsOf returns the dataAsOf value of a response: the latest earnings
+// timestamp, or nil when nothing has been stored yet.
+func dataAsOf(c *gin.Context, db *gorm.DB) (interface{}, error) {
+	asOf, err := c.MustGet("freshness").(*dataFreshness).get(db)
+	if err != nil {
+		return nil, err
+	}
+	return formatOptionalTime(asOf), nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/metrics"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLatestEarningTime(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+
+	latest, err := latestEarningTime(db)
+	if err != nil || !latest.IsZero() {
+		t.Fatalf("without earnings: %s, %v; want the zero time", latest, err)
+	}
+
+	newest := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	earnings := []models.ClientEarning{
+		{ClientAddress: testWallet, Earnings: 1, Timestamp: newest.Add(-time.Hour)},
+		{ClientAddress: testWallet, Earnings: 1, Timestamp: newest},
+	}
+	if err := db.Create(&earnings).Error; err != nil {
+		t.Fatal(err)
+	}
+	if latest, err = latestEarningTime(db); err != nil || !latest.Equal(newest) {
+		t.Errorf("latest earning at %s, %v; want %s", latest, err, newest)
+	}
+}
+
+func TestIngestionLag(t *testing.T) {
+	now := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		latest time.Time
+		want   time.Duration
+		ok     bool
+	}{
+		{time.Time{}, 0, false}, // nothing stored yet
+		{now.Add(-90 * time.Second), 90 * time.Second, true},
+		{now, 0, true},
+		{now.Add(time.Minute), 0, true}, // clock skew
+	}
+	for _, tt := range tests {
+		if lag, ok := ingestionLag(tt.latest, now); lag != tt.want || ok != tt.ok {
+			t.Errorf("ingestionLag(%s) = %s, %v; want %s, %v", tt.latest, lag, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestIngestionLagFromSeededEarnings reads the lag of earnings stored 90s
+// ago through the endpoint and the gauge.
+func TestIngestionLagFromSeededEarnings(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+
+	body := decodeJSON(t, serve(newTestRouter(t, db, cfg), http.MethodGet, "/api/v1/network/ingestion-lag", ""), http.StatusOK)
+	if body["latestEarning"] != nil || body["ingestionLagSeconds"] != nil {
+		t.Errorf("without earnings: %v, want null latestEarning and lag", body)
+	}
+
+	latest := time.Now().UTC().Add(-90 * time.Second).Truncate(time.Second)
+	earnings := []models.ClientEarning{
+		{ClientAddress: testWallet, Earnings: 1, Timestamp: latest.Add(-time.Hour)},
+		{ClientAddress: testWallet, Earnings: 1, Timestamp: latest},
+	}
+	if err := db.Create(&earnings).Error; err != nil {
+		t.Fatal(err)
+	}
+	closeTo90s := func(seconds float64) bool { return math.Abs(seconds-90) < 5 }
+
+	// A new router, as the first one has cached the empty answer
+	body = decodeJSON(t, serve(newTestRouter(t, db, cfg), http.MethodGet, "/api/v1/network/ingestion-lag", ""), http.StatusOK)
+	if body["latestEarning"] != latest.Format(time.RFC3339) {
+		t.Errorf("latestEarning %v, want %s", body["latestEarning"], latest.Format(time.RFC3339))
+	}
+	if lag, ok := body["ingestionLagSeconds"].(float64); !ok || !closeTo90s(lag) {
+		t.Errorf("ingestionLagSeconds %v, want about 90", body["ingestionLagSeconds"])
+	}
+
+	metrics.IngestionLag.Set(-1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		updateIngestionLagGauge(ctx, db, quietLogger)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(metrics.IngestionLag) < 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if lag := testutil.ToFloat64(metrics.IngestionLag); !closeTo90s(lag) {
+		t.Errorf("ingestion_lag_seconds gauge %v, want about 90", lag)
+	}
+}
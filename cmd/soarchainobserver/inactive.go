@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"gorm.io/gorm"
+)
+
+// inactiveSweepInterval is how often clients are checked for inactivity
+const inactiveSweepInterval = time.Hour
+
+// sweepInactiveClients soft-deletes clients not challenged for longer than
+// inactiveAfter, once right away and then every inactiveSweepInterval until
+// ctx is cancelled.
+func sweepInactiveClients(ctx context.Context, db *gorm.DB, inactiveAfter time.Duration, logger *log.Logger) {
+	ticker := time.NewTicker(inactiveSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().UTC().Add(-inactiveAfter)
+		result := db.WithContext(ctx).
+			Where("last_challenge_time < ?", cutoff).
+			Delete(&models.Client{})
+		if result.Error != nil && ctx.Err() == nil {
+			logger.Printf("Error soft-deleting inactive clients: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			logger.Printf("Soft-deleted %d clients inactive since %s", result.RowsAffected, cutoff.Format(time.RFC3339))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
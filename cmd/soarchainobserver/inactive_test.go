@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestInactiveClientsAreHiddenFromListings(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	now := time.Now().UTC()
+	clients := []models.Client{
+		{Address: "soar1dead", LastChallengeTime: now.Add(-60 * 24 * time.Hour)},
+		{Address: "soar1down", LastChallengeTime: now.Add(-48 * time.Hour)},
+	}
+	if err := db.Create(&clients).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sweepInactiveClients(ctx, db, 30*24*time.Hour, quietLogger)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var active int64
+		if err := db.Model(&models.Client{}).Count(&active).Error; err != nil {
+			t.Fatal(err)
+		}
+		if active == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d active clients after the sweep, want 1", active)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// The soft-deleted client keeps its row
+	var dead models.Client
+	if err := db.Unscoped().First(&dead, "address = ?", "soar1dead").Error; err != nil || !dead.DeletedAt.Valid {
+		t.Fatalf("soar1dead: %+v, %v; want a soft-deleted row", dead, err)
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  float64
+	}{
+		{"status=down", 1},
+		{"status=down&includeInactive=true", 2},
+	} {
+		body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miners?"+tt.query, ""), http.StatusOK)
+		if body["total"] != tt.want {
+			t.Errorf("%s: %v miners, want %v", tt.query, body["total"], tt.want)
+		}
+	}
+	if w := serve(router, http.MethodGet, "/api/v1/miners?status=down&includeInactive=maybe", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid includeInactive: status %d, want 400", w.Code)
+	}
+}
@@ -1,24 +1,26 @@
 package main
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain/types"
 	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
 	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
 	"github.com/Soar-Robotics/SoarchainObserver/internal/utils"
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	"gorm.io/driver/postgres"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gorm.io/gorm"
 )
 
@@ -36,45 +38,37 @@ func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: No .env file found or error loading it")
 	}
+	if apiKey := os.Getenv("ADMIN_API_KEY"); apiKey != "" {
+		cfg.AdminAPIKey = apiKey
+	}
 
-	// Read database credentials from env
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		dbHost, dbUser, dbPassword, dbName, dbPort,
-	)
-
-	// Initialize database connection
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := openDatabase(cfg)
 	if err != nil {
-		logger.Fatalf("Failed to connect to database: %v", err)
+		logger.Fatalf("%v", err)
 	}
-
-	// Set up DB connection pooling
 	sqlDB, err := db.DB()
 	if err != nil {
 		logger.Fatalf("Failed to get database handle: %v", err)
 	}
-	sqlDB.SetMaxOpenConns(25)
-	sqlDB.SetMaxIdleConns(25)
-	sqlDB.SetConnMaxLifetime(5 * time.Minute)
 
-	// Migrate the schema
-	if err := db.AutoMigrate(
-		&models.Client{},
-		&models.ClientEarning{},
-		&models.EpochEarnings{},
-	); err != nil {
-		logger.Fatalf("Failed to migrate database schema: %v", err)
+	// Refuse to record data from a node of another chain
+	if err := blockchain.VerifyChainID(context.Background(), cfg, logger); err != nil {
+		logger.Fatalf("Chain check failed: %v", err)
+	}
+
+	// `soarchainobserver backfill --from-epoch N --to-epoch M` replays past
+	// epochs and exits instead of starting the observer
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		err := runBackfill(cfg, db, os.Args[2:], logger)
+		sqlDB.Close()
+		if err != nil {
+			logger.Fatalf("Backfill failed: %v", err)
+		}
+		return
 	}
 
 	// Initialize the BlockReader
-	blockReader, err := blockchain.NewBlockReader(cfg.RPCEndpoint, db)
+	blockReader, err := blockchain.NewBlockReader(cfg, db)
 	if err != nil {
 		logger.Fatalf("Failed to connect to WebSocket: %v", err)
 	}
@@ -83,461 +77,1137 @@ func main() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
+	// ctx is cancelled on shutdown so the reader stops touching the DB
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	warmup := newWarmupState(cfg)
+
+	srv := &http.Server{
+		Addr: ":8080",
+		Handler: setupRouter(routerDeps{
+			DB:          db,
+			BlockReader: blockReader,
+			Config:      cfg,
+			Logger:      logger,
+			Warmup:      warmup,
+		}),
+	}
+
+	// workers tracks the observer, API and background goroutines so the DB
+	// is only closed once all of them have returned.
+	workers := newSubsystems()
+
 	// Start the observer in a separate goroutine
-	go func() {
+	workers.Go("block reader", func() {
 		logger.Println("Connected to WebSocket, starting to read blocks...")
-		blockReader.ReadBlocks(logger)
-	}()
+		blockReader.ReadBlocks(ctx, logger)
+		logger.Println("Block reader stopped")
+	})
+
+	if cfg.WarmupEpochs > 0 {
+		workers.Go("warmup backfill", func() {
+			runWarmup(ctx, cfg, db, warmup, logger)
+		})
+	}
+
+	workers.Go("active miners gauge", func() {
+		updateActiveMinersGauge(ctx, db, logger)
+	})
+
+	workers.Go("ingestion lag gauge", func() {
+		updateIngestionLagGauge(ctx, db, logger)
+	})
+
+	if cfg.DryRun {
+		logger.Println("Dry run: earnings are logged, not stored; the inactive client sweep and epoch archiver are off")
+	}
+
+	if cfg.InactiveClientAfter.Duration > 0 && !cfg.DryRun {
+		workers.Go("inactive client sweep", func() {
+			sweepInactiveClients(ctx, db, cfg.InactiveClientAfter.Duration, logger)
+		})
+	}
+
+	if cfg.ArchiveEpochsAfterDays > 0 && !cfg.DryRun {
+		workers.Go("epoch archiver", func() {
+			archiveEpochs(ctx, db, time.Duration(cfg.ArchiveEpochsAfterDays)*24*time.Hour, logger)
+		})
+	}
 
 	// Start the API server in a separate goroutine
-	go func() {
-		router := setupRouter(db)
+	workers.Go("API server", func() {
 		logger.Println("Starting API server on port 8080")
-		if err := router.Run(":8080"); err != nil && err != http.ErrServerClosed {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Fatalf("Failed to run API server: %v", err)
 		}
-	}()
+		logger.Println("API server stopped")
+	})
 
 	// Block until a signal is received
 	<-stop
 
-	// Graceful shutdown
+	// Graceful shutdown: stop the reader, drain HTTP requests, then wait for
+	// the goroutines before releasing the DB, all within the shutdown
+	// timeout.
 	logger.Println("Shutting down observer...")
+	cancel()
+
+	deadline := time.Now().Add(cfg.ShutdownTimeout())
+	shutdownCtx, shutdownCancel := context.WithDeadline(context.Background(), deadline)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Printf("Error shutting down API server: %v", err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			logger.Printf("API server didn't drain within %s, closing open connections", cfg.ShutdownTimeout())
+			srv.Close()
+		}
+	}
 
-	// Close DB
+	if stuck := workers.WaitUntil(deadline); len(stuck) > 0 {
+		logger.Printf("Subsystems didn't stop within %s, closing the DB anyway: %s",
+			cfg.ShutdownTimeout(), strings.Join(stuck, ", "))
+	}
+
+	// Close DB, releasing the cached prepared statements first
+	if stmtDB, ok := db.ConnPool.(*gorm.PreparedStmtDB); ok {
+		stmtDB.Close()
+	}
 	if err := sqlDB.Close(); err != nil {
 		logger.Printf("Error closing DB: %v", err)
 	}
 }
 
+// routerDeps are the dependencies setupRouter makes available to handlers
+// through the gin context.
+type routerDeps struct {
+	DB          *gorm.DB
+	BlockReader *blockchain.BlockReader
+	Config      *config.Config
+	Logger      *log.Logger
+	Warmup      *warmupState
+}
+
 // setupRouter defines all the endpoints
-func setupRouter(db *gorm.DB) *gin.Engine {
-	router := gin.Default()
+func setupRouter(deps routerDeps) *gin.Engine {
+	cfg := deps.Config
+
+	router := gin.New()
+	// Only trusted proxies may set the client IP used by c.ClientIP();
+	// Validate has checked the entries
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		deps.Logger.Printf("Ignoring trusted proxies: %v", err)
+	}
+	router.RemoteIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+	router.Use(requestID())
+	router.Use(gin.Recovery())
+	router.Use(requestLogger(utils.GetStructuredLogger(), utils.ParseLogLevel(cfg.RequestLogLevel)))
+
+	// allow CORS, from the configured origins if any
+	router.Use(corsMiddleware(cfg.CORSAllowedOrigins))
+	if cfg.SecurityHeaders {
+		router.Use(securityHeaders())
+	}
 
-	// allow CORS
-	router.Use(cors.Default())
+	statusCache := newStatusCache(cfg.StatusCacheTTL.Duration, cfg.StatusCacheSize)
+	freshness := newDataFreshness(dataAsOfTTL)
+	amounts := newAmountFormatting(cfg)
 
-	// Inject DB into context
+	// Inject DB, block reader, config, amount formatting, logger and caches into context
 	router.Use(func(c *gin.Context) {
-		c.Set("db", db)
+		c.Set("db", deps.DB)
+		c.Set("blockReader", deps.BlockReader)
+		c.Set("config", cfg)
+		c.Set("amounts", amounts)
+		c.Set("logger", deps.Logger)
+		c.Set("warmup", deps.Warmup)
+		c.Set("statusCache", statusCache)
+		c.Set("freshness", freshness)
 		c.Next()
 	})
 
+	// readiness of the DB and the WebSocket observer
+	router.GET("/readyz", getReadiness)
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	// query by address
-	router.GET("/client/:address", getClientEarnings)
+	router.GET("/client/:address", getClientBy("address", "address", true))
 
 	// endpoints: query by solana address, pubkey
-	router.GET("/client/solana/:solanaAddress", getClientBySolanaAddress)
-	router.GET("/client/pubkey/:pubkey", getClientByPubKey)
+	router.GET("/client/solana/:solanaAddress", getClientBy("solana_address", "solanaAddress", true))
+	router.GET("/client/pubkey/:pubkey", getClientBy("pub_key", "pubkey", true))
+
+	// the same lookups with camelCase fields like every other endpoint
+	clients := router.Group("/api/v1/client")
+	{
+		clients.GET("/:address", getClientBy("address", "address", false))
+		clients.GET("/solana/:solanaAddress", getClientBy("solana_address", "solanaAddress", false))
+		clients.GET("/pubkey/:pubkey", getClientBy("pub_key", "pubkey", false))
+	}
 
 	// average earnings over a period
 	router.GET("/average", getAverageRewards)
 	router.GET("/timeframe-earnings", getTimeframeEarnings)
 
+	// endpoints reading epoch_earnings, unavailable without epoch aggregation
+	epochsOnly := requireEpochAggregation(cfg.EpochAggregation())
+
 	// New endpoints for daily aggregated status, latest rewards, and all rewards
 	group := router.Group("/api/v1/miner")
 	{
 		group.GET("/status", GetMinerStatus)
-		group.GET("/latest-rewards", GetLatestRewards)
-		group.GET("/all-rewards", GetAllRewards)
+		group.GET("/latest-rewards", epochsOnly, GetLatestRewards)
+		group.GET("/all-rewards", epochsOnly, GetAllRewards)
+		group.GET("/epoch", epochsOnly, GetEpochReward)
+		group.GET("/dashboard", GetMinerDashboard)
+		group.GET("/status-history", GetMinerStatusHistory)
+		group.GET("/summary", GetMinerSummary)
+		group.GET("/rank", getMinerRank)
+		group.GET("/earnings", getMinerEarnings)
+		group.GET("/lifespan", getMinerLifespan)
+	}
+
+	// public settings clients need to interpret responses
+	router.GET("/api/v1/config", getPublicConfig)
+
+	// clients by current status
+	router.GET("/api/v1/miners", getMiners)
+
+	// earnings aggregated across every client sharing a pubkey
+	router.GET("/api/v1/pubkey/:pubkey/earnings", getPubKeyEarnings)
+
+	// earnings of every client paying out to one solana address
+	router.GET("/api/v1/solana/:solanaAddress/aggregate", getSolanaAggregate)
+
+	// network-wide statistics
+	network := router.Group("/api/v1/network")
+	{
+		network.GET("/leaderboard", getLeaderboard)
+		network.GET("/active-miners", getActiveMiners)
+		network.GET("/ingestion-lag", getIngestionLag)
+		network.GET("/epoch-totals", epochsOnly, getEpochTotals)
+	}
+
+	// Admin endpoints, gated by the admin API key
+	admin := router.Group("/api/v1/admin", requireAdminKey(cfg.AdminAPIKey))
+	{
+		admin.POST("/epoch/:number/recompute", epochsOnly, recomputeEpoch)
+		admin.GET("/export", exportData)
+		admin.POST("/import", importData)
+		admin.DELETE("/client/:address", purgeClient)
+		admin.POST("/merge", mergeClients)
+		admin.POST("/repair-earnings", repairEarnings)
+		admin.POST("/earnings", ingestEarnings)
+		admin.POST("/prices", recordPrice)
+	}
+
+	// CPU/heap profiles, only mounted when explicitly enabled
+	if cfg.EnableProfiling {
+		registerProfiling(router, cfg.AdminAPIKey)
 	}
 
 	return router
 }
 
+// getReadiness handles GET /readyz.
+// It reports 503 when the database can't be reached or the startup warmup
+// is still running, and "degraded" when the epoch info is failing to
+// refresh, and exposes the observer's connection, epoch cache and warmup
+// state.
+func getReadiness(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	blockReader := c.MustGet("blockReader").(*blockchain.BlockReader)
+
+	stats := blockReader.ReconnectStats()
+	observer := gin.H{
+		"lastMessageAt":      formatOptionalTime(blockReader.LastMessageAt()),
+		"reconnections":      stats.Reconnections,
+		"lastReconnectAt":    formatOptionalTime(stats.LastReconnectAt),
+		"lastReconnectErr":   nilIfEmpty(stats.LastError),
+		"lastReconnectErrAt": formatOptionalTime(stats.LastErrorAt),
+	}
+
+	epochStale := blockReader.EpochStale()
+	identifiers := gin.H{}
+	for identifier, epochStatus := range blockReader.EpochStatus() {
+		entry := gin.H{
+			"currentEpoch":   nil,
+			"lastFetchedAt":  formatOptionalTime(epochStatus.LastFetchedAt),
+			"lastFetchError": nilIfEmpty(epochStatus.LastError),
+			"lastErrorAt":    formatOptionalTime(epochStatus.LastErrorAt),
+		}
+		if !epochStatus.LastFetchedAt.IsZero() {
+			entry["currentEpoch"] = epochStatus.Info.CurrentEpoch
+		}
+		identifiers[identifier] = entry
+	}
+	epoch := gin.H{
+		"stale":       epochStale,
+		"identifiers": identifiers,
+	}
+
+	warmup := c.MustGet("warmup").(*warmupState).get()
+
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.PingContext(c.Request.Context())
+	}
+	if err != nil {
+		// Probes aren't in the request log, and the driver error may name
+		// hosts or credentials, so it is only logged here
+		c.MustGet("logger").(*log.Logger).Printf("Readiness check: database unavailable: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "not ready",
+			"database": "unavailable",
+			"observer": observer,
+			"epoch":    epoch,
+			"warmup":   warmup,
+		})
+		return
+	}
+
+	// Hold traffic until the startup backfill has filled recent history
+	if warmup == warmupRunning {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "not ready",
+			"database": "ok",
+			"observer": observer,
+			"epoch":    epoch,
+			"warmup":   warmup,
+		})
+		return
+	}
+
+	// A stale epoch doesn't stop ingestion, but earnings may be aggregated
+	// into the wrong epoch until the epoch API recovers.
+	status := "ready"
+	if epochStale {
+		status = "degraded"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   status,
+		"database": "ok",
+		"observer": observer,
+		"epoch":    epoch,
+		"warmup":   warmup,
+	})
+}
+
+// formatOptionalTime renders t as RFC3339, or nil when it is unset.
+func formatOptionalTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
+
+// nilIfEmpty renders an empty string as JSON null.
+func nilIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
 // ---------------------------------------------------------------------
 // 1) /api/v1/miner/status
 // ---------------------------------------------------------------------
 
-// GetMinerStatus handles GET /api/v1/miner/status?wallet=<SOLANA_WALLET>
+// GetMinerStatus handles GET /api/v1/miner/status?wallet=<SOLANA_WALLET>&strict=true&fresh=true&identifier=day
+//
+// A wallet the observer has never recorded is "unknown"; a recorded client
+// whose last challenge is too old is "known but offline". By default both
+// answer 200 with a "Down" body, which keeps dashboards simple. With
+// strict=true an unknown wallet answers 404 instead, like the /client lookups.
+//
+// Lookups are cached briefly per wallet; fresh=true bypasses the cache.
+//
+// earnedThisEpoch tells an online miner that isn't earning apart from one
+// that is, for the current epoch of identifier (default "day").
 func GetMinerStatus(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	cache := c.MustGet("statusCache").(*statusCache)
 
-	solanaWallet := c.Query("wallet")
-	if solanaWallet == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'wallet' query param"})
+	solanaWallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
 		return
 	}
-
-	// Directly look up the Client record
-	var client models.Client
-	err := db.Where("solana_address = ?", solanaWallet).First(&client).Error
+	identifier, err := parseEpochIdentifier(c)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusOK, gin.H{
-				"status": "Down",
-				"issues": []string{"Offline"},
-				"logs":   gin.H{"lastSeen": nil},
-			})
+		respondParamError(c, err)
+		return
+	}
+
+	var client *models.Client
+	var cached bool
+	if c.Query("fresh") != "true" {
+		client, cached = cache.get(solanaWallet)
+	}
+	if !cached {
+		// Directly look up the Client record
+		var found models.Client
+		err := db.Unscoped().Where("solana_address = ?", solanaWallet).First(&found).Error
+		switch {
+		case err == nil:
+			client = &found
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			// Other DB error
+			respondDBError(c, db, err)
 			return
 		}
-		// Other DB error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		cache.put(solanaWallet, client)
+	}
+
+	if client == nil && c.Query("strict") == "true" {
+		respondError(c, http.StatusNotFound, "Client not found")
 		return
 	}
 
-	// If lastChallengeTime is zero => never challenged
-	if client.LastChallengeTime.IsZero() {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "Down",
-			"issues": []string{"Offline"},
-			"logs":   gin.H{"lastSeen": nil},
-		})
+	response := minerStatusResponse(client, c.MustGet("config").(*config.Config), c.MustGet("logger").(*log.Logger))
+	earned, err := earnedThisEpoch(c, db, client, solanaWallet, identifier)
+	if err != nil {
+		respondDBError(c, db, err)
 		return
 	}
+	response["earnedThisEpoch"] = earned
+	c.JSON(http.StatusOK, response)
+}
 
-	// Evaluate difference in minutes
-	now := time.Now().UTC()
-	diffMins := now.Sub(client.LastChallengeTime).Minutes()
+// earnedThisEpoch reports whether wallet has earnings in the current epoch
+// with the given identifier, as known from the cached epoch info so the
+// epoch API isn't called. It returns nil when that isn't known: without
+// epoch aggregation or before the epoch was first fetched.
+func earnedThisEpoch(c *gin.Context, db *gorm.DB, client *models.Client, wallet, identifier string) (interface{}, error) {
+	cfg := c.MustGet("config").(*config.Config)
+	blockReader := c.MustGet("blockReader").(*blockchain.BlockReader)
+	if !cfg.EpochAggregation() {
+		return nil, nil
+	}
+	epoch, ok := blockReader.CachedEpoch(identifier)
+	if !ok {
+		return nil, nil
+	}
+	if client == nil {
+		return false, nil
+	}
 
-	var status string
-	var issues []string
-	switch {
-	case diffMins <= 2:
-		status = "Up"
-	case diffMins > 2 && diffMins < 5:
-		status = "immobile"
-		issues = append(issues, "Latency")
-	default:
-		status = "Down"
-		issues = append(issues, "Offline")
+	var rows int64
+	err := db.Model(&models.EpochEarnings{}).
+		Where("client_address = ? AND identifier = ? AND epoch_number = ? AND start_time = ? AND total_earnings > 0",
+			wallet, identifier, epoch.CurrentEpoch, epoch.CurrentEpochStart.Truncate(time.Microsecond)).
+		Limit(1).
+		Count(&rows).Error
+	if err != nil {
+		return nil, err
 	}
+	return rows > 0, nil
+}
+
+// Reasons reported in logs.reason when a miner is Down
+const (
+	reasonUnknownWallet   = "unknown_wallet"   // no client recorded for the wallet
+	reasonNeverChallenged = "never_challenged" // client recorded without a challenge time
+	reasonOffline         = "offline"          // last challenge is too old
+)
+
+// minerStatusResponse builds the status body for a client, or for an unknown
+// wallet when client is nil.
+func minerStatusResponse(client *models.Client, cfg *config.Config, logger *log.Logger) gin.H {
+	if client == nil {
+		return gin.H{
+			"status": types.StatusDown,
+			"issues": []types.MinerIssue{types.IssueOffline},
+			"logs":   gin.H{"lastSeen": nil, "reason": reasonUnknownWallet},
+		}
+	}
+
+	// If lastChallengeTime is zero => never challenged
+	if client.LastChallengeTime.IsZero() {
+		return gin.H{
+			"status": types.StatusDown,
+			"issues": []types.MinerIssue{types.IssueOffline},
+			"logs":   gin.H{"lastSeen": nil, "reason": reasonNeverChallenged},
+		}
+	}
+
+	since := challengeAge(client, time.Now().UTC(), logger)
+	status, issues := minerStatusSince(since, cfg)
 
 	logs := gin.H{
 		"lastSeen": client.LastChallengeTime.Format(time.RFC3339),
-		"diffMins": diffMins,
+		"diffMins": since.Minutes(),
 	}
-	c.JSON(http.StatusOK, gin.H{
+	if status == types.StatusDown {
+		logs["reason"] = reasonOffline
+	}
+	return gin.H{
 		"status": status,
 		"issues": issues,
 		"logs":   logs,
-	})
+	}
+}
+
+// maxClockSkew is how far in the future a last challenge time may be
+// before it is logged as clock skew
+const maxClockSkew = 30 * time.Second
+
+// challengeAge returns how long before now the client was last challenged.
+// A challenge time in the future, from clock skew between the observer and
+// the chain, counts as just now and is logged to logger.
+func challengeAge(client *models.Client, now time.Time, logger *log.Logger) time.Duration {
+	since := now.Sub(client.LastChallengeTime)
+	if since >= 0 {
+		return since
+	}
+	if -since > maxClockSkew {
+		logger.Printf("Warning: last challenge time of client %s (%s) is %s in the future; check for clock skew",
+			client.Address, client.LastChallengeTime.Format(time.RFC3339), -since)
+	}
+	return 0
+}
+
+// minerStatusSince classifies a miner last challenged since ago: Up up to
+// StatusDegradedAfter, Down from StatusDownAfter and Degraded in between,
+// unless the Degraded band is disabled.
+func minerStatusSince(since time.Duration, cfg *config.Config) (types.MinerStatus, []types.MinerIssue) {
+	switch {
+	case since >= cfg.StatusDownAfter.Duration:
+		return types.StatusDown, []types.MinerIssue{types.IssueOffline}
+	case since <= cfg.StatusDegradedAfter.Duration || cfg.DisableDegradedStatus:
+		return types.StatusUp, nil
+	default:
+		return types.StatusDegraded, []types.MinerIssue{types.IssueHighLatency}
+	}
 }
 
 // ---------------------------------------------------------------------
 // 2) /api/v1/miner/latest-rewards
 // ---------------------------------------------------------------------
 
-// GetLatestRewards handles GET /api/v1/miner/latest-rewards?wallet=<SOLANA_WALLET>&limit=7
-// Returns up to 'limit' latest epoch records in descending order of epoch_number.
+// maxLatestRewardsLimit caps the number of epochs per request
+const maxLatestRewardsLimit = 100
+
+// GetLatestRewards handles GET /api/v1/miner/latest-rewards?wallet=<SOLANA_WALLET>&limit=7&minAmount=&identifier=day
+// Returns up to 'limit' latest epoch records in descending order of epoch_number,
+// skipping epochs that earned less than minAmount tokens.
 func GetLatestRewards(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
-	wallet := c.Query("wallet")
-	if wallet == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'wallet' query param"})
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	limit, err := parseLimit(c, 7, maxLatestRewardsLimit)
+	if err != nil {
+		respondParamError(c, err)
 		return
 	}
 
-	// Default limit to 7 if not provided
-	limitStr := c.Query("limit")
-	if limitStr == "" {
-		limitStr = "7"
+	minAmount, err := parseMinAmount(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
 	}
-	limitVal, err := strconv.Atoi(limitStr)
+
+	identifier, err := parseEpochIdentifier(c)
 	if err != nil {
-		limitVal = 7
+		respondParamError(c, err)
+		return
+	}
+
+	etag, err := rewardsETag(db, c, wallet)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+	if notModified(c, etag) {
+		return
 	}
 
 	var epochs []models.EpochEarnings
-	err = db.Where("client_address = ?", wallet).
+	err = epochRewards(db).Where("client_address = ? AND identifier = ? AND total_earnings >= ?", wallet, identifier, minAmount).
 		Order("epoch_number DESC").
-		Limit(limitVal).
+		Limit(limit).
 		Find(&epochs).Error
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondDBError(c, db, err)
 		return
 	}
 
-	// Build JSON response
-	results := make([]map[string]interface{}, 0, len(epochs))
-	for _, e := range epochs {
-		results = append(results, map[string]interface{}{
-			"epochNumber":   e.EpochNumber,
-			"startTime":     e.StartTime.Format(time.RFC3339),
-			"endTime":       e.EndTime.Format(time.RFC3339),
-			"totalEarnings": float64(e.TotalEarnings) / 1e6, // if micro-based
-			"tokenSymbol":   "SOAR",
-		})
+	prices, err := loadEpochPrices(db, tokenSymbol(c), epochs)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, results)
+	c.JSON(http.StatusOK, epochRewardsResponse(epochs, tokenSymbol(c), amountFormat(c), prices))
 }
 
 // ---------------------------------------------------------------------
 // 3) /api/v1/miner/all-rewards
 // ---------------------------------------------------------------------
 
-// GetAllRewards handles GET /api/v1/miner/all-rewards?wallet=<SOLANA_WALLET>
-// It returns *daily aggregated* earnings for each calendar day.
+// GetAllRewards handles GET /api/v1/miner/all-rewards?wallet=<SOLANA_WALLET>&fromEpoch=&toEpoch=&minAmount=&identifier=day
+// It returns *daily aggregated* earnings for each calendar day, optionally
+// limited to an inclusive epoch range and to epochs earning at least minAmount.
 func GetAllRewards(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
-	wallet := c.Query("wallet")
-	if wallet == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'wallet' query param"})
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
 		return
 	}
 
-	var epochs []models.EpochEarnings
-	if err := db.Where("client_address = ?", wallet).
+	epochs, err := parseEpochRange(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	minAmount, err := parseMinAmount(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	identifier, err := parseEpochIdentifier(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	etag, err := rewardsETag(db, c, wallet)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+	if notModified(c, etag) {
+		return
+	}
+
+	query := epochs.apply(epochRewards(db).Where("client_address = ? AND identifier = ? AND total_earnings >= ?", wallet, identifier, minAmount))
+
+	var records []models.EpochEarnings
+	if err := query.
 		Order("epoch_number ASC").
-		Find(&epochs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		Find(&records).Error; err != nil {
+		respondDBError(c, db, err)
 		return
 	}
 
+	prices, err := loadEpochPrices(db, tokenSymbol(c), records)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, epochRewardsResponse(records, tokenSymbol(c), amountFormat(c), prices))
+}
+
+// epochRewardsResponse renders epoch records in the shape shared by the
+// reward endpoints.
+func epochRewardsResponse(epochs []models.EpochEarnings, symbol string, format amountFormatting, prices priceBook) []map[string]interface{} {
 	results := make([]map[string]interface{}, 0, len(epochs))
 	for _, e := range epochs {
-		results = append(results, map[string]interface{}{
-			"epochNumber":   e.EpochNumber,
-			"startTime":     e.StartTime.Format(time.RFC3339),
-			"endTime":       e.EndTime.Format(time.RFC3339),
-			"totalEarnings": float64(e.TotalEarnings) / 1e6, // if micro-based
-			"tokenSymbol":   "SOAR",
-		})
+		results = append(results, epochRewardResponse(e, symbol, format, prices))
 	}
-	c.JSON(http.StatusOK, results)
+	return results
 }
 
-// ---------------------------------------------------------------------
-// Additional existing endpoints
-// ---------------------------------------------------------------------
+// epochRewardResponse renders a single epoch record, with amounts in the
+// token named by symbol rendered with format. usdValue is the total at the
+// price when the epoch ended.
+func epochRewardResponse(e models.EpochEarnings, symbol string, format amountFormatting, prices priceBook) map[string]interface{} {
+	return map[string]interface{}{
+		"identifier":    e.Identifier,
+		"epochNumber":   e.EpochNumber,
+		"startTime":     e.StartTime.Format(time.RFC3339),
+		"endTime":       e.EndTime.Format(time.RFC3339),
+		"totalEarnings": format.amount(e.TotalEarnings),
+		"tokenSymbol":   symbol,
+		"usdValue":      prices.usdValue(format, e.TotalEarnings, e.EndTime),
+	}
+}
 
-func getClientBySolanaAddress(c *gin.Context) {
+// GetEpochReward handles GET /api/v1/miner/epoch?wallet=<SOLANA_WALLET>&epoch=33&identifier=day
+// It returns the wallet's earnings in one epoch, or 404 when the wallet
+// didn't earn anything in it. When the number was reused after a chain
+// reset, the latest epoch with that number is returned.
+func GetEpochReward(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
-	solanaAddress := c.Param("solanaAddress")
-	period := c.Query("period")
-
-	var client models.Client
-	result := db.First(&client, "solana_address = ?", solanaAddress)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	epochNumber, err := strconv.ParseInt(c.Query("epoch"), 10, 64)
+	if err != nil || epochNumber < 0 {
+		respondError(c, http.StatusBadRequest, "Invalid 'epoch' query param")
+		return
+	}
+
+	identifier, err := parseEpochIdentifier(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	var epoch models.EpochEarnings
+	err = epochRewards(db).
+		Where("client_address = ? AND identifier = ? AND epoch_number = ?", wallet, identifier, epochNumber).
+		Order("start_time DESC").
+		Take(&epoch).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, http.StatusNotFound, "No earnings for this wallet in the epoch")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		respondDBError(c, db, err)
+		return
+	}
+
+	prices, err := loadEpochPrices(db, tokenSymbol(c), []models.EpochEarnings{epoch})
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, epochRewardResponse(epoch, tokenSymbol(c), amountFormat(c), prices))
+}
+
+// ---------------------------------------------------------------------
+// 4) /api/v1/miner/dashboard
+// ---------------------------------------------------------------------
+
+// GetMinerDashboard handles GET /api/v1/miner/dashboard?wallet=<SOLANA_WALLET>&identifier=day
+// It combines the status, the last 7 epochs and the lifetime total so the
+// frontend needs a single call. The "status" and "latestRewards" parts have
+// the same shape as the dedicated endpoints; "latestRewards" is null when
+// epoch aggregation is disabled.
+func GetMinerDashboard(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	cfg := c.MustGet("config").(*config.Config)
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
 		return
 	}
 
-	// Default period to last 1 hour if not specified
-	if period == "" {
-		period = "1h"
+	var client *models.Client
+	var found models.Client
+	err = db.Unscoped().Where("solana_address = ?", wallet).First(&found).Error
+	switch {
+	case err == nil:
+		client = &found
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		respondDBError(c, db, err)
+		return
 	}
-	duration, err := time.ParseDuration(period)
+
+	identifier, err := parseEpochIdentifier(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period format"})
+		respondParamError(c, err)
 		return
 	}
 
-	endTime := time.Now().UTC()
-	startTime := endTime.Add(-duration)
+	// The client lookup is needed for everything else; the other parts are
+	// left out with a warning when they fail
+	var warnings partialWarnings
+
+	// Without epoch aggregation there are no epochs to report
+	var latestRewards interface{}
+	if cfg.EpochAggregation() {
+		var epochs []models.EpochEarnings
+		if err := epochRewards(db).Where("client_address = ? AND identifier = ?", wallet, identifier).
+			Order("epoch_number DESC").
+			Limit(7).
+			Find(&epochs).Error; err != nil {
+			warnings.add(c, "latestRewards", err)
+		} else {
+			prices, err := loadEpochPrices(db, tokenSymbol(c), epochs)
+			if err != nil {
+				warnings.add(c, "usdValue", err)
+			}
+			latestRewards = epochRewardsResponse(epochs, tokenSymbol(c), amountFormat(c), prices)
+		}
+	}
 
-	var earningsOverPeriod int64
-	db.Model(&models.ClientEarning{}).
-		Where("client_address = ? AND timestamp BETWEEN ? AND ?", client.SolanaAddress, startTime, endTime).
-		Select("COALESCE(SUM(earnings), 0)").Scan(&earningsOverPeriod)
+	var lifetime int64
+	if client != nil {
+		lifetime = client.TotalLifetimeEarnings
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"solana_address":          client.SolanaAddress,
-		"address":                 client.Address,
-		"pubkey":                  client.PubKey,
-		"total_lifetime_earnings": client.TotalLifetimeEarnings,
-		"earnings_over_period":    earningsOverPeriod,
-		"period":                  period,
+		"wallet":                wallet,
+		"status":                minerStatusResponse(client, cfg, c.MustGet("logger").(*log.Logger)),
+		"latestRewards":         latestRewards,
+		"totalLifetimeEarnings": amountFormat(c).amount(lifetime),
+		"tokenSymbol":           tokenSymbol(c),
+		"warnings":              warnings.list(),
 	})
 }
 
-func getClientByPubKey(c *gin.Context) {
+// ---------------------------------------------------------------------
+// 5) /api/v1/miner/status-history
+// ---------------------------------------------------------------------
+
+// maxStatusHistoryLimit caps the number of transitions per request
+const maxStatusHistoryLimit = 200
+
+// GetMinerStatusHistory handles GET /api/v1/miner/status-history?wallet=<SOLANA_WALLET>&limit=20
+// It returns the most recent Up/Down transitions of the wallet's clients,
+// newest first, or an empty list when none were recorded.
+func GetMinerStatusHistory(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
-	pubkey := c.Param("pubkey")
-	period := c.Query("period")
-
-	var client models.Client
-	result := db.First(&client, "pub_key = ?", pubkey)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
 		return
 	}
 
-	// Default period to last 1 hour if not specified
-	if period == "" {
-		period = "1h"
-	}
-	duration, err := time.ParseDuration(period)
+	limit, err := parseLimit(c, 20, maxStatusHistoryLimit)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period format"})
+		respondParamError(c, err)
 		return
 	}
 
-	endTime := time.Now().UTC()
-	startTime := endTime.Add(-duration)
+	// Transitions are keyed by the core address of the wallet's clients
+	var transitions []models.StatusTransition
+	err = db.Where("client_address IN (?)",
+		db.Unscoped().Model(&models.Client{}).Select("address").Where("solana_address = ?", wallet)).
+		Order("timestamp DESC, id DESC").
+		Limit(limit).
+		Find(&transitions).Error
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
 
-	var earningsOverPeriod int64
-	db.Model(&models.ClientEarning{}).
-		Where("client_address = ? AND timestamp BETWEEN ? AND ?", client.SolanaAddress, startTime, endTime).
-		Select("COALESCE(SUM(earnings), 0)").Scan(&earningsOverPeriod)
+	history := make([]gin.H, 0, len(transitions))
+	for _, transition := range transitions {
+		history = append(history, gin.H{
+			"address":   transition.ClientAddress,
+			"status":    transition.Status,
+			"timestamp": transition.Timestamp.Format(time.RFC3339),
+		})
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"address":                 client.Address,
-		"pubkey":                  client.PubKey,
-		"solana_address":          client.SolanaAddress,
-		"total_lifetime_earnings": client.TotalLifetimeEarnings,
-		"earnings_over_period":    earningsOverPeriod,
-		"period":                  period,
+		"wallet":      wallet,
+		"transitions": history,
 	})
 }
 
-func getAverageRewards(c *gin.Context) {
+// ---------------------------------------------------------------------
+// 6) /api/v1/miner/summary
+// ---------------------------------------------------------------------
+
+// GetMinerSummary handles GET /api/v1/miner/summary?wallet=<SOLANA_WALLET>&identifier=day
+// It returns the lifetime earnings, the earnings since midnight UTC, the
+// earnings of the current epoch, the last challenge time and the status in
+// one call. A wallet never seen answers with zero amounts and a Down status;
+// currentEpoch is null when epoch aggregation is disabled.
+func GetMinerSummary(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	blockReader := c.MustGet("blockReader").(*blockchain.BlockReader)
+	cfg := c.MustGet("config").(*config.Config)
 
-	// 1) Read period from query, default to "1h"
-	period := c.Query("period")
-	if period == "" {
-		period = "1h"
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
 	}
-
-	// 2) Parse it as a Go duration
-	duration, err := time.ParseDuration(period)
+	identifier, err := parseEpochIdentifier(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid period format: %v", err)})
+		respondParamError(c, err)
+		return
+	}
+
+	var client *models.Client
+	var found models.Client
+	err = db.Unscoped().Where("solana_address = ?", wallet).First(&found).Error
+	switch {
+	case err == nil:
+		client = &found
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		respondDBError(c, db, err)
 		return
 	}
 
-	// 3) Calculate the start/end times
-	endTime := time.Now().UTC()
-	startTime := endTime.Add(-duration)
+	var lifetime int64
+	var lastSeen interface{}
+	if client != nil {
+		lifetime = client.TotalLifetimeEarnings
+		lastSeen = formatOptionalTime(client.LastChallengeTime)
+	}
+
+	// The client lookup is needed for everything else; the other parts are
+	// left out with a warning when they fail
+	var warnings partialWarnings
+
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var today int64
+	var todayEarnings interface{}
+	if err := db.Model(&models.ClientEarning{}).
+		Where("client_address = ? AND timestamp >= ?", wallet, midnight).
+		Select("COALESCE(SUM(earnings), 0)").
+		Scan(&today).Error; err != nil {
+		warnings.add(c, "todayEarnings", err)
+	} else {
+		todayEarnings = amountFormat(c).amount(today)
+	}
+
+	// The current epoch is only known once the epoch API has answered, and
+	// isn't reported at all without epoch aggregation
+	var currentEpoch gin.H
+	if cfg.EpochAggregation() {
+		currentEpoch = gin.H{
+			"identifier":    identifier,
+			"epochNumber":   nil,
+			"totalEarnings": amountFormat(c).amount(0),
+		}
+	}
+	if status, ok := blockReader.EpochStatus()[identifier]; ok && currentEpoch != nil && !status.LastFetchedAt.IsZero() {
+		currentEpoch["epochNumber"] = status.Info.CurrentEpoch
+
+		var epoch models.EpochEarnings
+		err := epochRewards(db).
+			Where("client_address = ? AND identifier = ? AND epoch_number = ? AND start_time = ?",
+				wallet, identifier, status.Info.CurrentEpoch, status.Info.CurrentEpochStart.Truncate(time.Microsecond)).
+			Take(&epoch).Error
+		switch {
+		case err == nil:
+			currentEpoch["totalEarnings"] = amountFormat(c).amount(epoch.TotalEarnings)
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			currentEpoch["totalEarnings"] = nil
+			warnings.add(c, "currentEpoch.totalEarnings", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"wallet":           wallet,
+		"status":           minerStatusResponse(client, cfg, c.MustGet("logger").(*log.Logger)),
+		"lastSeen":         lastSeen,
+		"lifetimeEarnings": amountFormat(c).amount(lifetime),
+		"todayEarnings":    todayEarnings,
+		"currentEpoch":     currentEpoch,
+		"tokenSymbol":      tokenSymbol(c),
+		"warnings":         warnings.list(),
+	})
+}
+
+// ---------------------------------------------------------------------
+// Additional existing endpoints
+// ---------------------------------------------------------------------
+
+// legacyClientFields maps the camelCase client fields to the snake_case
+// names of the original /client routes
+var legacyClientFields = map[string]string{
+	"solanaAddress":         "solana_address",
+	"totalLifetimeEarnings": "total_lifetime_earnings",
+	"earningsOverPeriod":    "earnings_over_period",
+	"earningsPerHour":       "earnings_per_hour",
+	"expectedChallenges":    "expected_challenges",
+	"startTime":             "start_time",
+	"endTime":               "end_time",
+}
+
+// getClientBy returns the handler of a /client lookup route: it finds the
+// client whose column equals the route param and reports its lifetime
+// earnings and its earnings, challenges and availability over the period.
+// The three lookup routes (core address, solana address, pubkey) answer in
+// the same shape.
+//
+// Fields are camelCase, as on the /api/v1 routes. The original /client
+// routes set legacy to keep the snake_case names their consumers rely on.
+func getClientBy(column, param string, legacy bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		db := c.MustGet("db").(*gorm.DB)
+		cfg := c.MustGet("config").(*config.Config)
+
+		var client models.Client
+		result := db.Unscoped().First(&client, column+" = ?", c.Param(param))
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				respondError(c, http.StatusNotFound, "Client not found")
+				return
+			}
+			respondDBError(c, db, result.Error)
+			return
+		}
+
+		// Default period to last 1 hour if not specified
+		window, err := parsePeriodWindow(c, "1h")
+		if err != nil {
+			respondParamError(c, err)
+			return
+		}
 
-	// 4) Query the average between startTime and endTime
+		var period periodEarnings
+		if err := db.Model(&models.ClientEarning{}).
+			Where("client_address = ? AND timestamp BETWEEN ? AND ?", client.EarningsAddress(), window.Start, window.End).
+			Select("COALESCE(SUM(earnings), 0) AS total_earnings, COUNT(*) AS challenges").
+			Scan(&period).Error; err != nil {
+			respondDBError(c, db, err)
+			return
+		}
+		expected := cfg.ExpectedChallenges(window.Duration())
+
+		body := gin.H{
+			"address":               client.Address,
+			"pubkey":                client.PubKey,
+			"solanaAddress":         client.SolanaAddress,
+			"totalLifetimeEarnings": microAmount(client.TotalLifetimeEarnings),
+			"earningsOverPeriod":    microAmount(period.TotalEarnings),
+			"earningsPerHour":       earningsPerHour(period.TotalEarnings, window.Duration()),
+			"challenges":            period.Challenges,
+			"expectedChallenges":    expected,
+			"availability":          availability(period.Challenges, expected),
+			"period":                window.Period,
+			"align":                 window.Align,
+			"startTime":             window.Start.Format(time.RFC3339),
+			"endTime":               window.End.Format(time.RFC3339),
+		}
+		if legacy {
+			for camel, snake := range legacyClientFields {
+				body[snake] = body[camel]
+				delete(body, camel)
+			}
+		}
+		c.JSON(http.StatusOK, body)
+	}
+}
+
+func getAverageRewards(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	// 1) Read the period and alignment from the query, default to "1h"
+	window, err := parsePeriodWindow(c, "1h")
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	// 2) Query the average and the number of samples between startTime and
+	// endTime. An empty window averages to 0 with a sampleCount of 0.
 	var result struct {
 		AvgEarnings int64 `gorm:"column:avg_earnings"`
+		SampleCount int64 `gorm:"column:sample_count"`
 	}
 
 	query := `
-        SELECT AVG(earnings)::int AS avg_earnings
+        SELECT CAST(ROUND(COALESCE(AVG(earnings), 0)) AS BIGINT) AS avg_earnings,
+               COUNT(*) AS sample_count
         FROM client_earnings
         WHERE timestamp BETWEEN ? AND ?
     `
-	if err := db.Raw(query, startTime, endTime).Scan(&result).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := db.Raw(query, window.Start, window.End).Scan(&result).Error; err != nil {
+		respondDBError(c, db, err)
 		return
 	}
 
-	// 5) Return the JSON
+	// 3) Return the JSON
 	c.JSON(http.StatusOK, gin.H{
-		"average":   float64(result.AvgEarnings) / 1000000.0,
-		"period":    period,
-		"startTime": startTime.Format(time.RFC3339),
-		"endTime":   endTime.Format(time.RFC3339),
+		"average":     amountFormat(c).amount(result.AvgEarnings),
+		"sampleCount": result.SampleCount,
+		"period":      window.Period,
+		"align":       window.Align,
+		"startTime":   window.Start.Format(time.RFC3339),
+		"endTime":     window.End.Format(time.RFC3339),
 	})
 }
 
 // getTimeframeEarnings handles:
-// GET /timeframe-earnings?wallet=<WALLET>&period=<duration>
-// If period is not provided, it defaults to "1h".
-// Interprets the sum of challenges in that window as the total if uptime is 100%.
+// GET /timeframe-earnings?wallet=<WALLET>&period=<duration>&align=rolling
+// If period is not provided, it defaults to "1h". Like every period endpoint
+// it takes align=calendar to start the window on a calendar boundary.
+// Interprets the sum of challenges in that window as the total if uptime is 100%,
+// and reports the availability: the share of the challenges expected in the
+// window (Config.ExpectedChallenges) that were rewarded.
 func getTimeframeEarnings(c *gin.Context) {
 	db := c.MustGet("db").(*gorm.DB)
+	cfg := c.MustGet("config").(*config.Config)
 
-	wallet := c.Query("wallet")
-	if wallet == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing 'wallet' query param"})
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
 		return
 	}
 
-	periodStr := c.Query("period")
-	if periodStr == "" {
-		periodStr = "1h" // default
-	}
-
-	// Parse Go duration, e.g. "1h", "30m", "2h"
-	dur, err := time.ParseDuration(periodStr)
+	// Define the timeframe from the period, e.g. "1h", "30m", "2h"
+	window, err := parsePeriodWindow(c, "1h")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid period format: %v", err)})
+		respondParamError(c, err)
 		return
 	}
 
-	// Define the timeframe: [startTime, endTime]
-	endTime := time.Now().UTC()
-	startTime := endTime.Add(-dur)
-
 	// Query the DB to sum up all earnings in that interval
-	var result struct {
-		TotalEarnings int64 `gorm:"column:total_earnings"`
-	}
+	var result periodEarnings
 
 	query := `
-        SELECT COALESCE(SUM(earnings), 0) AS total_earnings
+        SELECT COALESCE(SUM(earnings), 0) AS total_earnings, COUNT(*) AS challenges
         FROM client_earnings
         WHERE client_address = ?
           AND timestamp BETWEEN ? AND ?
     `
-	if err := db.Raw(query, wallet, startTime, endTime).Scan(&result).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := db.Raw(query, wallet, window.Start, window.End).Scan(&result).Error; err != nil {
+		respondDBError(c, db, err)
 		return
 	}
 
-	// Convert from integer micro-units to float if needed
-	// e.g., if 1,000,000 micro = 1 token
-	totalFloat := float64(result.TotalEarnings) / 1e6
-
-	if wallet == "7z72VqEfUtccgw4dJWmzEPw9jx8r9EU1yoa8HZJEUmWP" {
-		totalFloat = totalFloat * 0.85
-	}
+	// Wallet-specific adjustments (e.g. payout shares) come from config
+	adjustmentFactor := cfg.AdjustmentFactor(wallet)
+	expected := cfg.ExpectedChallenges(window.Duration())
 
 	// Return JSON
 	c.JSON(http.StatusOK, gin.H{
-		"wallet":           wallet,
-		"period":           periodStr,
-		"start":            startTime.Format(time.RFC3339),
-		"end":              endTime.Format(time.RFC3339),
-		"estimatedEarning": totalFloat, // "if 100% uptime in this window"
-		"tokenSymbol":      "SOAR",
+		"wallet":             wallet,
+		"period":             window.Period,
+		"align":              window.Align,
+		"startTime":          window.Start.Format(time.RFC3339),
+		"endTime":            window.End.Format(time.RFC3339),
+		"start":              window.Start.Format(time.RFC3339), // kept for clients predating startTime
+		"end":                window.End.Format(time.RFC3339),
+		"rawEarning":         amountFormat(c).amount(result.TotalEarnings),
+		"adjustmentFactor":   adjustmentFactor,
+		"estimatedEarning":   amountFormat(c).amount(int64(math.Round(float64(result.TotalEarnings) * adjustmentFactor))), // "if 100% uptime in this window"
+		"challenges":         result.Challenges,
+		"expectedChallenges": expected,
+		"availability":       availability(result.Challenges, expected),
+		"tokenSymbol":        tokenSymbol(c),
 	})
 }
 
-// getClientEarnings queries by core address
-func getClientEarnings(c *gin.Context) {
-	db := c.MustGet("db").(*gorm.DB)
-	address := c.Param("address")
-	period := c.Query("period") // e.g., "1h", "24h"
-
-	var client models.Client
-	result := db.First(&client, "address = ?", address)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
-		return
-	}
+// periodEarnings is the sum and number of a wallet's earnings rows over a
+// period, one row per rewarded challenge
+type periodEarnings struct {
+	TotalEarnings int64
+	Challenges    int64
+}
 
-	// Default period to last 1 hour if not specified
-	if period == "" {
-		period = "1h"
+// availability is the share of the expected challenges that were rewarded,
+// capped at 1, or nil when the period is shorter than the expected
+// challenge interval.
+func availability(challenges, expected int64) interface{} {
+	if expected <= 0 {
+		return nil
 	}
-	duration, err := time.ParseDuration(period)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period format"})
-		return
-	}
-
-	endTime := time.Now().UTC()
-	startTime := endTime.Add(-duration)
-
-	var earningsOverPeriod int64
-	db.Model(&models.ClientEarning{}).
-		Where("client_address = ? AND timestamp BETWEEN ? AND ?", client.SolanaAddress, startTime, endTime).
-		Select("COALESCE(SUM(earnings), 0)").Scan(&earningsOverPeriod)
+	return math.Min(float64(challenges)/float64(expected), 1)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"address":                 client.Address,
-		"pubkey":                  client.PubKey,
-		"total_lifetime_earnings": client.TotalLifetimeEarnings,
-		"earnings_over_period":    earningsOverPeriod,
-		"period":                  period,
-	})
+// earningsPerHour normalises earnings over a period to an hourly rate, in the
+// same micro-units as the earnings. Sub-hour periods scale up; an empty or
+// zero-length period yields 0.
+func earningsPerHour(earnings int64, period time.Duration) float64 {
+	hours := period.Hours()
+	if hours <= 0 || earnings == 0 {
+		return 0
+	}
+	return float64(earnings) / hours
 }
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain/types"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAvailability(t *testing.T) {
+	tests := []struct {
+		challenges, expected int64
+		want                 interface{}
+	}{
+		{30, 60, 0.5},
+		{60, 60, 1.0},
+		{75, 60, 1.0}, // capped
+		{0, 60, 0.0},
+		{3, 0, nil}, // period shorter than the interval
+	}
+	for _, tt := range tests {
+		if got := availability(tt.challenges, tt.expected); got != tt.want {
+			t.Errorf("availability(%d, %d) = %v, want %v", tt.challenges, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestEarningsRateHandlersReportAvailability(t *testing.T) {
+	cfg := loadTestConfig(t, `{"expected_challenge_interval": "2m"}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	now := time.Now().UTC()
+	if err := db.Create(&models.Client{Address: "soar1a", SolanaAddress: testWallet, LastChallengeTime: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 15 challenges over the last hour, where 30 are expected
+	var earnings []models.ClientEarning
+	for i := 0; i < 15; i++ {
+		earnings = append(earnings, models.ClientEarning{
+			ClientAddress: testWallet, CoreAddress: "soar1a", Earnings: 1000,
+			Timestamp: now.Add(-time.Duration(i*4+1) * time.Minute),
+		})
+	}
+	if err := db.Create(&earnings).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"/timeframe-earnings?wallet=" + testWallet + "&period=1h",
+		"/api/v1/client/soar1a?period=1h",
+		"/client/soar1a?period=1h",
+	} {
+		body := decodeJSON(t, serve(router, http.MethodGet, path, ""), http.StatusOK)
+		expected := "expectedChallenges"
+		if strings.HasPrefix(path, "/client/") {
+			expected = "expected_challenges"
+		}
+		if body["challenges"] != 15.0 || body[expected] != 30.0 || body["availability"] != 0.5 {
+			t.Errorf("%s: challenges %v of %v expected, availability %v; want 15 of 30, 0.5",
+				path, body["challenges"], body[expected], body["availability"])
+		}
+	}
+}
+
+func TestWalletHandlersValidateTheWallet(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	for _, path := range []string{
+		"/api/v1/miner/epoch?epoch=1",
+		"/api/v1/miner/dashboard?",
+		"/api/v1/miner/status-history?",
+		"/api/v1/miner/summary?",
+		"/timeframe-earnings?period=1h",
+	} {
+		body := decodeJSON(t, serve(router, http.MethodGet, path, ""), http.StatusBadRequest)
+		if body["error"] != "missing 'wallet' query param" {
+			t.Errorf("%s without a wallet: error %v", path, body["error"])
+		}
+		body = decodeJSON(t, serve(router, http.MethodGet, path+"&wallet=not-a-wallet", ""), http.StatusBadRequest)
+		if body["error"] != `invalid 'wallet' query param: "not-a-wallet" is not a solana address` {
+			t.Errorf("%s with an invalid wallet: error %v", path, body["error"])
+		}
+	}
+}
+
+func TestGetMinerStatusStrictMode(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: &blockchain.BlockReader{}, // no epoch fetched yet
+		Config:      cfg,
+		Logger:      quietLogger,
+		Warmup:      newWarmupState(cfg),
+	})
+
+	offline := testWallet
+	lastSeen := time.Now().UTC().Add(-48 * time.Hour)
+	if err := db.Create(&models.Client{Address: "soar1offline", SolanaAddress: offline, LastChallengeTime: lastSeen}).Error; err != nil {
+		t.Fatal(err)
+	}
+	unknown := "Unknown111111111111111111111111111"
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantReason string
+	}{
+		{"unknown wallet", "wallet=" + unknown, http.StatusOK, reasonUnknownWallet},
+		{"unknown wallet, strict", "wallet=" + unknown + "&strict=true", http.StatusNotFound, ""},
+		{"known but offline", "wallet=" + offline, http.StatusOK, reasonOffline},
+		{"known but offline, strict", "wallet=" + offline + "&strict=true", http.StatusOK, reasonOffline},
+	}
+	for _, tt := range tests {
+		body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miner/status?"+tt.query, ""), tt.wantStatus)
+		if tt.wantStatus == http.StatusNotFound {
+			if body["error"] != "Client not found" {
+				t.Errorf("%s: error %v", tt.name, body["error"])
+			}
+			continue
+		}
+		logs, _ := body["logs"].(map[string]interface{})
+		if body["status"] != "Down" || logs["reason"] != tt.wantReason {
+			t.Errorf("%s: status %v, reason %v; want Down, %s", tt.name, body["status"], logs["reason"], tt.wantReason)
+		}
+	}
+}
+
+func TestMinerStatusResponseReasons(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	now := time.Now().UTC()
+	tests := []struct {
+		name       string
+		client     *models.Client
+		wantStatus types.MinerStatus
+		wantReason interface{}
+	}{
+		{"unknown wallet", nil, types.StatusDown, reasonUnknownWallet},
+		{"never challenged", &models.Client{Address: "soar1new"}, types.StatusDown, reasonNeverChallenged},
+		{"offline", &models.Client{Address: "soar1old", LastChallengeTime: now.Add(-48 * time.Hour)}, types.StatusDown, reasonOffline},
+		{"up", &models.Client{Address: "soar1up", LastChallengeTime: now.Add(-time.Minute)}, types.StatusUp, nil},
+	}
+	for _, tt := range tests {
+		response := minerStatusResponse(tt.client, cfg, quietLogger)
+		logs := response["logs"].(gin.H)
+		if response["status"] != tt.wantStatus || logs["reason"] != tt.wantReason {
+			t.Errorf("%s: status %v, reason %v; want %v, %v", tt.name, response["status"], logs["reason"], tt.wantStatus, tt.wantReason)
+		}
+	}
+}
+
+func TestGetAverageRewards(t *testing.T) {
+	cfg := loadTestConfig(t, `{"token_decimals": 6}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	// An empty window averages to 0 over no samples
+	body := decodeJSON(t, serve(router, http.MethodGet, "/average?period=1h", ""), http.StatusOK)
+	if body["average"] != 0.0 || body["sampleCount"] != 0.0 {
+		t.Errorf("empty window: average %v over %v samples, want 0 over 0", body["average"], body["sampleCount"])
+	}
+
+	now := time.Now().UTC()
+	earnings := []models.ClientEarning{
+		{ClientAddress: testWallet, Earnings: 1000000, Timestamp: now.Add(-10 * time.Minute)},
+		{ClientAddress: testWallet, Earnings: 2000001, Timestamp: now.Add(-20 * time.Minute)},
+		{ClientAddress: testWallet, Earnings: 9000000, Timestamp: now.Add(-2 * time.Hour)}, // outside the window
+	}
+	if err := db.Create(&earnings).Error; err != nil {
+		t.Fatal(err)
+	}
+	body = decodeJSON(t, serve(router, http.MethodGet, "/average?period=1h", ""), http.StatusOK)
+	if body["average"] != 1.500001 || body["sampleCount"] != 2.0 {
+		t.Errorf("average %v over %v samples, want 1.500001 over 2", body["average"], body["sampleCount"])
+	}
+}
+
+func TestMinerStatusSinceBoundaries(t *testing.T) {
+	defaults := loadTestConfig(t, `{}`)
+	custom := loadTestConfig(t, `{"status_degraded_after": "1m", "status_down_after": "10m"}`)
+	noDegraded := loadTestConfig(t, `{"disable_degraded_status": true}`)
+
+	tests := []struct {
+		name  string
+		cfg   *config.Config
+		since time.Duration
+		want  types.MinerStatus
+		issue types.MinerIssue
+	}{
+		{"default, just challenged", defaults, 0, types.StatusUp, ""},
+		{"default, exactly 2m", defaults, 2 * time.Minute, types.StatusUp, ""},
+		{"default, just past 2m", defaults, 2*time.Minute + time.Second, types.StatusDegraded, types.IssueHighLatency},
+		{"default, just before 5m", defaults, 5*time.Minute - time.Second, types.StatusDegraded, types.IssueHighLatency},
+		{"default, exactly 5m", defaults, 5 * time.Minute, types.StatusDown, types.IssueOffline},
+		{"custom, exactly 1m", custom, time.Minute, types.StatusUp, ""},
+		{"custom, 5m", custom, 5 * time.Minute, types.StatusDegraded, types.IssueHighLatency},
+		{"custom, exactly 10m", custom, 10 * time.Minute, types.StatusDown, types.IssueOffline},
+		{"no degraded band, 4m", noDegraded, 4 * time.Minute, types.StatusUp, ""},
+		{"no degraded band, exactly 5m", noDegraded, 5 * time.Minute, types.StatusDown, types.IssueOffline},
+	}
+	for _, tt := range tests {
+		status, issues := minerStatusSince(tt.since, tt.cfg)
+		var issue types.MinerIssue
+		if len(issues) > 0 {
+			issue = issues[0]
+		}
+		if status != tt.want || issue != tt.issue || len(issues) > 1 {
+			t.Errorf("%s: %s %v, want %s [%s]", tt.name, status, issues, tt.want, tt.issue)
+		}
+	}
+}
+
+// TestFutureChallengeTime serves the status of a client whose last
+// challenge time is ahead of the observer's clock.
+func TestFutureChallengeTime(t *testing.T) {
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+
+	now := time.Now().UTC()
+	tests := []struct {
+		lastChallenge time.Time
+		want          time.Duration
+		warn          bool
+	}{
+		{now.Add(-3 * time.Minute), 3 * time.Minute, false},
+		{now, 0, false},
+		{now.Add(maxClockSkew), 0, false},
+		{now.Add(time.Hour), 0, true},
+	}
+	for _, tt := range tests {
+		logs.Reset()
+		client := &models.Client{Address: "soar1skewed", LastChallengeTime: tt.lastChallenge}
+		if got := challengeAge(client, now, logger); got != tt.want {
+			t.Errorf("challenged at %s: age %s, want %s", tt.lastChallenge, got, tt.want)
+		}
+		if warned := strings.Contains(logs.String(), "clock skew"); warned != tt.warn {
+			t.Errorf("challenged at %s: warned %v, want %v (log %q)", tt.lastChallenge, warned, tt.warn, logs.String())
+		}
+	}
+
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: &blockchain.BlockReader{},
+		Config:      cfg,
+		Logger:      quietLogger,
+		Warmup:      newWarmupState(cfg),
+	})
+	if err := db.Create(&models.Client{Address: "soar1skewed", SolanaAddress: testWallet,
+		LastChallengeTime: now.Add(time.Hour)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miner/status?wallet="+testWallet, ""), http.StatusOK)
+	if body["status"] != string(types.StatusUp) {
+		t.Errorf("status %v, want %s", body["status"], types.StatusUp)
+	}
+}
+
+func TestEarnedThisEpoch(t *testing.T) {
+	epochStart := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	node := fakeChainNode(t, epochStart)
+	cfg := loadTestConfig(t, fakeChainConfig(node, ""))
+	db := openTestDB(t, cfg)
+	blockReader, err := blockchain.NewBlockReader(cfg, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: blockReader,
+		Config:      cfg,
+		Logger:      quietLogger,
+		Warmup:      newWarmupState(cfg),
+	})
+
+	const earning, idle = testWallet, "Bob1111111111111111111111111111111"
+	now := time.Now().UTC()
+	for address, wallet := range map[string]string{"soar1earning": earning, "soar1idle": idle} {
+		if err := db.Create(&models.Client{Address: address, SolanaAddress: wallet, LastChallengeTime: now}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	earned := func(wallet string) interface{} {
+		t.Helper()
+		body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miner/status?fresh=true&wallet="+wallet, ""), http.StatusOK)
+		return body["earnedThisEpoch"]
+	}
+
+	// Unknown until the epoch has been fetched
+	if got := earned(earning); got != nil {
+		t.Errorf("earnedThisEpoch %v before the epoch was fetched, want null", got)
+	}
+	if _, err := blockReader.CurrentEpochs(quietLogger); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := epochStart.Add(-24 * time.Hour)
+	rows := []models.EpochEarnings{
+		{ClientAddress: earning, Identifier: "day", EpochNumber: 10, StartTime: epochStart, EndTime: epochStart.Add(24 * time.Hour), TotalEarnings: 100},
+		{ClientAddress: idle, Identifier: "day", EpochNumber: 9, StartTime: previous, EndTime: epochStart, TotalEarnings: 100},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		wallet string
+		want   interface{}
+	}{
+		{earning, true},
+		{idle, false}, // online, but only earned in the previous epoch
+		{"Unknown111111111111111111111111111", false},
+	} {
+		if got := earned(tt.wallet); got != tt.want {
+			t.Errorf("%s: earnedThisEpoch %v, want %v", tt.wallet, got, tt.want)
+		}
+	}
+}
+
+func TestClientLookupRoutes(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	now := time.Now().UTC()
+	if err := db.Create(&models.Client{Address: "soar1alice", PubKey: "pkalice", SolanaAddress: testWallet,
+		TotalLifetimeEarnings: 5000, LastChallengeTime: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.ClientEarning{ClientAddress: testWallet, CoreAddress: "soar1alice",
+		Earnings: 700, Timestamp: now.Add(-10 * time.Minute)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, prefix := range []string{"/client", "/api/v1/client"} {
+		legacy := prefix == "/client"
+		for _, lookup := range []string{"/soar1alice", "/solana/" + testWallet, "/pubkey/pkalice"} {
+			path := prefix + lookup
+			body := decodeJSON(t, serve(router, http.MethodGet, path, ""), http.StatusOK)
+			solana, lifetime, period := "solanaAddress", "totalLifetimeEarnings", "earningsOverPeriod"
+			if legacy {
+				solana, lifetime, period = "solana_address", "total_lifetime_earnings", "earnings_over_period"
+			}
+			if body["address"] != "soar1alice" || body["pubkey"] != "pkalice" || body[solana] != testWallet ||
+				body[lifetime] != "5000" || body[period] != "700" || body["challenges"] != 1.0 {
+				t.Errorf("%s: %v", path, body)
+			}
+			if _, ok := body["solanaAddress"]; legacy && ok {
+				t.Errorf("%s: legacy route has camelCase fields", path)
+			}
+		}
+		for _, lookup := range []string{"/soar1nobody", "/solana/Unknown111111111111111111111111111", "/pubkey/pknobody"} {
+			if w := serve(router, http.MethodGet, prefix+lookup, ""); w.Code != http.StatusNotFound {
+				t.Errorf("%s: status %d, want 404", prefix+lookup, w.Code)
+			}
+		}
+	}
+}
+
+// TestResponseFieldCasing walks every key of the /api/v1 responses, which
+// are camelCase, and of the legacy /client routes, which are snake_case.
+func TestResponseFieldCasing(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: &blockchain.BlockReader{},
+		Config:      cfg,
+		Logger:      quietLogger,
+		Warmup:      newWarmupState(cfg),
+	})
+	now := time.Now().UTC()
+	start := now.Truncate(24 * time.Hour)
+	if err := db.Create(&models.Client{Address: "soar1alice", PubKey: "pkalice", SolanaAddress: testWallet,
+		TotalLifetimeEarnings: 5000, LastChallengeTime: now}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.ClientEarning{ClientAddress: testWallet, CoreAddress: "soar1alice",
+		Earnings: 700, Timestamp: now.Add(-10 * time.Minute)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.EpochEarnings{ClientAddress: testWallet, Identifier: "day", EpochNumber: 1,
+		StartTime: start, EndTime: start.Add(24 * time.Hour), TotalEarnings: 700}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	camelCase := regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+	snakeCase := regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	wallet := "?wallet=" + testWallet
+	for _, tt := range []struct {
+		path  string
+		style *regexp.Regexp
+	}{
+		{"/api/v1/miner/status" + wallet, camelCase},
+		{"/api/v1/miner/latest-rewards" + wallet, camelCase},
+		{"/api/v1/miner/all-rewards" + wallet, camelCase},
+		{"/api/v1/miner/epoch" + wallet + "&epoch=1", camelCase},
+		{"/api/v1/miner/dashboard" + wallet, camelCase},
+		{"/api/v1/miner/status-history" + wallet, camelCase},
+		{"/api/v1/miner/summary" + wallet, camelCase},
+		{"/api/v1/miner/rank" + wallet, camelCase},
+		{"/api/v1/miner/earnings" + wallet, camelCase},
+		{"/api/v1/miner/lifespan" + wallet, camelCase},
+		{"/api/v1/config", camelCase},
+		{"/api/v1/miners?status=Up", camelCase},
+		{"/api/v1/pubkey/pkalice/earnings", camelCase},
+		{"/api/v1/solana/" + testWallet + "/aggregate", camelCase},
+		{"/api/v1/network/leaderboard", camelCase},
+		{"/api/v1/network/active-miners", camelCase},
+		{"/api/v1/network/ingestion-lag", camelCase},
+		{"/api/v1/network/epoch-totals", camelCase},
+		{"/api/v1/client/soar1alice", camelCase},
+		{"/client/soar1alice", snakeCase},
+		{"/client/solana/" + testWallet, snakeCase},
+		{"/client/pubkey/pkalice", snakeCase},
+	} {
+		w := serve(router, http.MethodGet, tt.path, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", tt.path, w.Code, w.Body)
+			continue
+		}
+		var body interface{}
+		decodeJSONInto(t, w, &body)
+		for _, key := range jsonKeys(body) {
+			if !tt.style.MatchString(key) {
+				t.Errorf("%s: field %q doesn't match %s", tt.path, key, tt.style)
+			}
+		}
+	}
+}
+
+// jsonKeys returns the object keys of a decoded JSON value, at any depth.
+func jsonKeys(value interface{}) []string {
+	var keys []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			keys = append(keys, key)
+			keys = append(keys, jsonKeys(field)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			keys = append(keys, jsonKeys(item)...)
+		}
+	}
+	return keys
+}
+
+func TestPeriodResponsesReportTheirWindow(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+	if err := db.Create(&models.Client{Address: "soar1alice", PubKey: "pkalice", SolanaAddress: testWallet,
+		LastChallengeTime: time.Now().UTC()}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{
+		"/client/soar1alice",
+		"/client/solana/" + testWallet,
+		"/client/pubkey/pkalice",
+		"/api/v1/client/soar1alice",
+		"/api/v1/client/solana/" + testWallet,
+		"/api/v1/client/pubkey/pkalice",
+		"/average",
+		"/timeframe-earnings?wallet=" + testWallet,
+	} {
+		sep := "?"
+		if strings.Contains(path, "?") {
+			sep = "&"
+		}
+		for _, tt := range []struct {
+			query string
+			check func(start, end time.Time) bool
+		}{
+			{"period=2h", func(start, end time.Time) bool { return end.Sub(start) == 2*time.Hour }},
+			{"period=1d&align=calendar", func(start, end time.Time) bool { return start.Equal(end.Truncate(24 * time.Hour)) }},
+		} {
+			before := time.Now().UTC().Truncate(time.Second)
+			body := decodeJSON(t, serve(router, http.MethodGet, path+sep+tt.query, ""), http.StatusOK)
+			startField, endField := "startTime", "endTime"
+			if strings.HasPrefix(path, "/client/") {
+				startField, endField = "start_time", "end_time"
+			}
+			start, errStart := time.Parse(time.RFC3339, fmt.Sprint(body[startField]))
+			end, errEnd := time.Parse(time.RFC3339, fmt.Sprint(body[endField]))
+			if errStart != nil || errEnd != nil {
+				t.Errorf("%s?%s: %s %v, %s %v are not RFC 3339", path, tt.query, startField, body[startField], endField, body[endField])
+				continue
+			}
+			if end.Before(before) || end.After(time.Now().UTC()) || !tt.check(start, end) {
+				t.Errorf("%s?%s: window %s - %s", path, tt.query, start, end)
+			}
+		}
+	}
+}
+
+func TestReadinessHidesDatabaseErrors(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	var logs bytes.Buffer
+	router := setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: &blockchain.BlockReader{},
+		Config:      cfg,
+		Logger:      log.New(&logs, "", 0),
+		Warmup:      newWarmupState(cfg),
+	})
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	w := serve(router, http.MethodGet, "/readyz", "")
+	body := decodeJSON(t, w, http.StatusServiceUnavailable)
+	if body["database"] != "unavailable" {
+		t.Errorf("database %v, want unavailable", body["database"])
+	}
+	if strings.Contains(w.Body.String(), "closed") {
+		t.Errorf("readiness body exposes the driver error: %s", w.Body)
+	}
+	if !strings.Contains(logs.String(), "database is closed") {
+		t.Errorf("log %q doesn't have the driver error", logs.String())
+	}
+}
+
+// TestReusedEpochNumbers stores epoch 10 of a chain that was reset and the
+// current epoch 10 and expects the lookups to tell them apart.
+func TestReusedEpochNumbers(t *testing.T) {
+	epochStart := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	node := fakeChainNode(t, epochStart)
+	cfg := loadTestConfig(t, fakeChainConfig(node, ""))
+	db := openTestDB(t, cfg)
+	blockReader, err := blockchain.NewBlockReader(cfg, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blockReader.CurrentEpochs(quietLogger); err != nil {
+		t.Fatal(err)
+	}
+	router := setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: blockReader,
+		Config:      cfg,
+		Logger:      quietLogger,
+		Warmup:      newWarmupState(cfg),
+	})
+
+	oldStart := epochStart.AddDate(0, -3, 0)
+	epochs := []models.EpochEarnings{
+		{ClientAddress: testWallet, Identifier: "day", EpochNumber: 10, StartTime: oldStart, EndTime: oldStart.Add(24 * time.Hour), TotalEarnings: 9_000_000},
+		{ClientAddress: testWallet, Identifier: "day", EpochNumber: 10, StartTime: epochStart, EndTime: epochStart.Add(24 * time.Hour), TotalEarnings: 2_000_000},
+	}
+	if err := db.Create(&epochs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miner/epoch?wallet="+testWallet+"&epoch=10", ""), http.StatusOK)
+	if body["totalEarnings"] != 2.0 || body["startTime"] != epochStart.Format(time.RFC3339) {
+		t.Errorf("epoch 10 = %v, want the current one earning 2", body)
+	}
+
+	body = decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miner/summary?wallet="+testWallet, ""), http.StatusOK)
+	current := body["currentEpoch"].(map[string]interface{})
+	if current["epochNumber"] != 10.0 || current["totalEarnings"] != 2.0 {
+		t.Errorf("summary currentEpoch = %v, want epoch 10 earning 2", current)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mergeRequest is the body of POST /api/v1/admin/merge: the client merged
+// away and the client it is merged into, both core addresses
+type mergeRequest struct {
+	From string `json:"from" binding:"required"`
+	Into string `json:"into" binding:"required"`
+}
+
+// mergeConflictError is a merge refused because the two clients can't be
+// the same miner. It is answered with 409.
+type mergeConflictError struct {
+	Message string
+}
+
+func (e *mergeConflictError) Error() string {
+	return e.Message
+}
+
+// mergeClients handles POST /api/v1/admin/merge
+// It merges two client records the chain reported for one miner under
+// different core addresses into the "into" client, in one transaction: the
+// earnings, epoch and status rows of "from" are re-keyed, lifetime totals
+// summed and the "from" client removed. Both clients must share a pubkey,
+// and may not be bound to different solana addresses.
+func mergeClients(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	var req mergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid merge request: %v", err))
+		return
+	}
+	if !coreAddressPattern.MatchString(req.From) || !coreAddressPattern.MatchString(req.Into) {
+		respondError(c, http.StatusBadRequest, "Invalid client address")
+		return
+	}
+	if req.From == req.Into {
+		respondError(c, http.StatusBadRequest, "Can't merge a client into itself")
+		return
+	}
+
+	var merged models.Client
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var from, into models.Client
+		locked := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"})
+		if err := locked.First(&from, "address = ?", req.From).Error; err != nil {
+			return err
+		}
+		if err := locked.First(&into, "address = ?", req.Into).Error; err != nil {
+			return err
+		}
+
+		if from.PubKey == "" || from.PubKey != into.PubKey {
+			return &mergeConflictError{Message: "Clients don't share a pubkey"}
+		}
+		if from.SolanaAddress != "" && into.SolanaAddress != "" && from.SolanaAddress != into.SolanaAddress {
+			return &mergeConflictError{Message: "Clients are bound to different solana addresses"}
+		}
+
+		// Earnings rows are keyed by the solana address, shared with any
+		// other client using it, so they only move when "from" is the
+		// sole client under its address.
+		target := into.SolanaAddress
+		if target == "" {
+			target = from.SolanaAddress
+		}
+		if target == "" {
+			target = into.Address
+		}
+		if source := from.EarningsAddress(); source != target {
+			var sharing int64
+			if err := tx.Unscoped().Model(&models.Client{}).
+				Where("solana_address = ? AND address <> ?", source, from.Address).
+				Count(&sharing).Error; err != nil {
+				return err
+			}
+			if sharing > 0 {
+				return &mergeConflictError{Message: "Earnings of the client being merged are shared with other clients"}
+			}
+		}
+		if err := blockchain.ReattributeEarnings(tx, from.EarningsAddress(), target); err != nil {
+			return err
+		}
+		if err := blockchain.ReattributeEarnings(tx, into.EarningsAddress(), target); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.ClientEarning{}).
+			Where("core_address = ?", from.Address).
+			Update("core_address", into.Address).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.StatusTransition{}).
+			Where("client_address = ?", from.Address).
+			Update("client_address", into.Address).Error; err != nil {
+			return err
+		}
+
+		into.SolanaAddress = target
+		if target == into.Address {
+			into.SolanaAddress = ""
+		}
+		into.TotalLifetimeEarnings += from.TotalLifetimeEarnings
+		into.ChallengeCount += from.ChallengeCount
+		if from.CreatedAt.Before(into.CreatedAt) {
+			into.CreatedAt = from.CreatedAt
+		}
+		if from.LastChallengeTime.After(into.LastChallengeTime) {
+			into.LastChallengeTime = from.LastChallengeTime
+		}
+		// The merged miner is active if either record was
+		if !from.DeletedAt.Valid {
+			into.DeletedAt = gorm.DeletedAt{}
+		}
+		if err := tx.Unscoped().Save(&into).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&from).Error; err != nil {
+			return err
+		}
+		merged = into
+		return nil
+	})
+	if err != nil {
+		var conflict *mergeConflictError
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			respondError(c, http.StatusNotFound, "Client not found")
+		case errors.As(err, &conflict):
+			respondError(c, http.StatusConflict, conflict.Message)
+		default:
+			respondDBError(c, db, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"merged":                req.From,
+		"address":               merged.Address,
+		"pubkey":                merged.PubKey,
+		"solanaAddress":         merged.SolanaAddress,
+		"totalLifetimeEarnings": microAmount(merged.TotalLifetimeEarnings),
+		"firstSeen":             formatOptionalTime(merged.CreatedAt),
+		"lastChallengeTime":     formatOptionalTime(merged.LastChallengeTime),
+		"challengeCount":        merged.ChallengeCount,
+		"active":                !merged.DeletedAt.Valid,
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// requestIDKey is the gin context key holding the request ID
+const requestIDKey = "requestID"
+
+// requestIDHeader carries the request ID in both directions
+const requestIDHeader = "X-Request-ID"
+
+// requestID takes the caller's X-Request-ID (or generates a UUID), stores it
+// in the context and echoes it back so logs and responses can be correlated.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = uuid.NewString()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// quietPaths are probe and scrape endpoints left out of the request log
+var quietPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// requestLogger logs every request as a structured line with its method,
+// path, status, latency, client IP and request ID. Successful requests are
+// logged at successLevel, 4xx at warn and 5xx at error.
+func requestLogger(logger *slog.Logger, successLevel slog.Level) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if quietPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := successLevel
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("clientIP", c.ClientIP()),
+			slog.String("requestID", c.GetString(requestIDKey)),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		logger.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+	}
+}
+
+// requireAdminKey rejects requests whose X-API-Key header doesn't match
+// apiKey. With an empty apiKey every request is rejected, so admin routes
+// stay closed unless a key has been configured.
+func requireAdminKey(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey == "" {
+			respondError(c, http.StatusForbidden, "Admin API is disabled")
+			return
+		}
+		provided := c.GetHeader("X-API-Key")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(apiKey)) != 1 {
+			respondError(c, http.StatusUnauthorized, "Invalid or missing API key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// securityHeaders sets the standard hardening headers on every response:
+// no MIME sniffing, no framing and no referrer leaking to other origins.
+func securityHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("X-Frame-Options", "DENY")
+		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
+		c.Next()
+	}
+}
+
+// corsMiddleware allows any origin when allowedOrigins is empty, and only
+// the listed origins otherwise.
+func corsMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	if len(allowedOrigins) == 0 {
+		return cors.Default()
+	}
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = allowedOrigins
+	corsConfig.AddAllowHeaders(requestIDHeader)
+	corsConfig.AddExposeHeaders(requestIDHeader)
+	return cors.New(corsConfig)
+}
+
+// requireEpochAggregation answers 501 on endpoints built on epoch_earnings
+// when epoch aggregation is disabled, instead of reporting no rewards.
+func requireEpochAggregation(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			respondErrorWith(c, http.StatusNotImplemented, "Epoch aggregation is disabled on this observer",
+				gin.H{"code": "epoch_aggregation_disabled"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/gin-gonic/gin"
+)
+
+func TestClientIPBehindTrustedProxies(t *testing.T) {
+	cfg := loadTestConfig(t, `{"trusted_proxies": ["10.0.0.0/8"]}`)
+	db := openTestDB(t, cfg)
+	router := setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: &blockchain.BlockReader{},
+		Config:      cfg,
+		Logger:      quietLogger,
+		Warmup:      newWarmupState(cfg),
+	})
+	router.GET("/test/client-ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"direct", "203.0.113.7:4000", nil, "203.0.113.7"},
+		{"forwarded by a trusted proxy", "10.1.2.3:4000",
+			map[string]string{"X-Forwarded-For": "203.0.113.7, 10.4.5.6"}, "203.0.113.7"},
+		{"real IP from a trusted proxy", "10.1.2.3:4000",
+			map[string]string{"X-Real-IP": "203.0.113.7"}, "203.0.113.7"},
+		{"forwarded by an untrusted peer", "198.51.100.9:4000",
+			map[string]string{"X-Forwarded-For": "203.0.113.7"}, "198.51.100.9"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/test/client-ip", nil)
+		req.RemoteAddr = tt.remoteAddr
+		for name, value := range tt.headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if got := w.Body.String(); got != tt.want {
+			t.Errorf("%s: client IP %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRequestLoggerLogsTheForwardedIP(t *testing.T) {
+	var logs bytes.Buffer
+	router := gin.New()
+	if err := router.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(requestLogger(slog.New(slog.NewTextHandler(&logs, nil)), slog.LevelInfo))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = "10.1.2.3:4000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(logs.String(), "clientIP=203.0.113.7") {
+		t.Errorf("log %q doesn't attribute the request to 203.0.113.7", logs.String())
+	}
+}
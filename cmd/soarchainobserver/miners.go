@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain/types"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// parseMinerStatus reads the required status query param, case-insensitively.
+func parseMinerStatus(c *gin.Context) (types.MinerStatus, error) {
+	raw := c.Query("status")
+	for _, status := range []types.MinerStatus{types.StatusUp, types.StatusDegraded, types.StatusDown} {
+		if strings.EqualFold(raw, string(status)) {
+			return status, nil
+		}
+	}
+	if raw == "" {
+		return "", fmt.Errorf("missing 'status' query param")
+	}
+	return "", fmt.Errorf("invalid 'status' query param: must be up, degraded or down")
+}
+
+// whereMinerStatus restricts query to clients whose last challenge puts
+// them in status at now, using the same bands as minerStatusSince. Each band
+// is a range on last_challenge_time so the index can serve it.
+func whereMinerStatus(query *gorm.DB, status types.MinerStatus, now time.Time, cfg *config.Config) *gorm.DB {
+	downBefore := now.Add(-cfg.StatusDownAfter.Duration)
+	degradedBefore := now.Add(-cfg.StatusDegradedAfter.Duration)
+	switch {
+	case status == types.StatusDown:
+		// Never challenged clients have a zero time, which falls in here
+		return query.Where("last_challenge_time <= ?", downBefore)
+	case cfg.DisableDegradedStatus && status == types.StatusDegraded:
+		return query.Where("1 = 0")
+	case cfg.DisableDegradedStatus:
+		return query.Where("last_challenge_time > ?", downBefore)
+	case status == types.StatusDegraded:
+		return query.Where("last_challenge_time > ? AND last_challenge_time < ?", downBefore, degradedBefore)
+	default:
+		return query.Where("last_challenge_time >= ?", degradedBefore)
+	}
+}
+
+// getMiners handles GET /api/v1/miners?status=down&page=&pageSize=
+// It lists the clients currently in the given status, most recently seen
+// first.
+func getMiners(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	cfg := c.MustGet("config").(*config.Config)
+
+	status, err := parseMinerStatus(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+	page, err := parsePagination(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+	includeInactive, err := parseIncludeInactive(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+	scoped := db
+	if includeInactive {
+		scoped = db.Unscoped()
+	}
+
+	now := time.Now().UTC()
+	query := whereMinerStatus(scoped.Model(&models.Client{}), status, now, cfg).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	var clients []models.Client
+	err = page.apply(query.Order("last_challenge_time DESC, address")).Find(&clients).Error
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	logger := c.MustGet("logger").(*log.Logger)
+	miners := make([]gin.H, 0, len(clients))
+	for i := range clients {
+		client := &clients[i]
+		var diffMins interface{}
+		if !client.LastChallengeTime.IsZero() {
+			diffMins = challengeAge(client, now, logger).Minutes()
+		}
+		miners = append(miners, gin.H{
+			"address":       client.Address,
+			"pubkey":        client.PubKey,
+			"solanaAddress": nilIfEmpty(client.SolanaAddress),
+			"lastSeen":      formatOptionalTime(client.LastChallengeTime),
+			"diffMins":      diffMins,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   status,
+		"page":     page.Page,
+		"pageSize": page.PageSize,
+		"total":    total,
+		"miners":   miners,
+	})
+}
+
+// minerLifespan is the tenure of the clients behind a wallet
+type minerLifespan struct {
+	Clients        int64
+	FirstSeen      time.Time
+	LastSeen       time.Time
+	ChallengeCount int64
+}
+
+// activeDuration is the time between the first and last challenge.
+func (l minerLifespan) activeDuration() time.Duration {
+	if l.LastSeen.Before(l.FirstSeen) {
+		return 0
+	}
+	return l.LastSeen.Sub(l.FirstSeen)
+}
+
+// getMinerLifespan handles GET /api/v1/miner/lifespan?wallet=
+// It returns when the wallet's miner was first and last seen, the time
+// between the two and the number of challenges it was rewarded for, over
+// all clients bound to the wallet, inactive ones included.
+func getMinerLifespan(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	var lifespan minerLifespan
+	err = db.Unscoped().Model(&models.Client{}).
+		Select("COUNT(*) AS clients, MIN(created_at) AS first_seen, "+
+			"MAX(last_challenge_time) AS last_seen, COALESCE(SUM(challenge_count), 0) AS challenge_count").
+		Where("solana_address = ?", wallet).
+		Take(&lifespan).Error
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+	if lifespan.Clients == 0 {
+		respondError(c, http.StatusNotFound, "Client not found")
+		return
+	}
+
+	active := lifespan.activeDuration()
+	c.JSON(http.StatusOK, gin.H{
+		"wallet":                wallet,
+		"clients":               lifespan.Clients,
+		"firstSeen":             formatOptionalTime(lifespan.FirstSeen),
+		"lastSeen":              formatOptionalTime(lifespan.LastSeen),
+		"activeDurationSeconds": int64(active.Seconds()),
+		"activeDuration":        active.String(),
+		"challengeCount":        lifespan.ChallengeCount,
+	})
+}
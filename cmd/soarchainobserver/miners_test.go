@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"gorm.io/gorm"
+)
+
+const testWallet = "9xQeWvG816bUx9EPjHmaT23yvVM2ZWbrrpZb9PusVFin"
+
+func TestGetMinerLifespan(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clients := []models.Client{
+		{Address: "soar1first", SolanaAddress: testWallet, CreatedAt: base, LastChallengeTime: base.Add(24 * time.Hour), ChallengeCount: 10},
+		// inactive clients count towards the lifespan
+		{Address: "soar1second", SolanaAddress: testWallet, CreatedAt: base.Add(12 * time.Hour), LastChallengeTime: base.Add(36 * time.Hour), ChallengeCount: 5,
+			DeletedAt: gorm.DeletedAt{Time: base.Add(48 * time.Hour), Valid: true}},
+		{Address: "soar1other", SolanaAddress: "Other1111111111111111111111111111", CreatedAt: base.Add(-time.Hour), LastChallengeTime: base.Add(time.Hour), ChallengeCount: 99},
+	}
+	if err := db.Create(&clients).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/miner/lifespan?wallet="+testWallet, ""), http.StatusOK)
+	want := map[string]interface{}{
+		"wallet":                testWallet,
+		"clients":               2.0,
+		"firstSeen":             "2025-01-01T00:00:00Z",
+		"lastSeen":              "2025-01-02T12:00:00Z",
+		"activeDurationSeconds": 36 * 3600.0,
+		"activeDuration":        "36h0m0s",
+		"challengeCount":        15.0,
+	}
+	for key, value := range want {
+		if body[key] != value {
+			t.Errorf("%s = %v, want %v", key, body[key], value)
+		}
+	}
+
+	if w := serve(router, http.MethodGet, "/api/v1/miner/lifespan?wallet=Unknown111111111111111111111111111", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown wallet: status %d, want 404", w.Code)
+	}
+	if w := serve(router, http.MethodGet, "/api/v1/miner/lifespan?wallet=not-a-wallet", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid wallet: status %d, want 400", w.Code)
+	}
+}
+
+// TestGetMinersMatchesStatusBands lists miners by status around the band
+// boundaries, which must agree with the status of a single miner.
+func TestGetMinersMatchesStatusBands(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	// Offsets stay clear of the boundaries so the test doesn't depend on
+	// the time between creating the rows and listing them
+	now := time.Now().UTC()
+	clients := map[string]time.Duration{
+		"soar1up":       time.Minute,
+		"soar1degraded": 3 * time.Minute,
+		"soar1down":     10 * time.Minute,
+	}
+	for address, since := range clients {
+		if err := db.Create(&models.Client{Address: address, LastChallengeTime: now.Add(-since)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for status, want := range map[string]string{"up": "soar1up", "degraded": "soar1degraded", "down": "soar1down"} {
+		var body struct {
+			Miners []struct{ Address string }
+		}
+		decodeJSONInto(t, serve(router, http.MethodGet, "/api/v1/miners?status="+status, ""), &body)
+		if len(body.Miners) != 1 || body.Miners[0].Address != want {
+			t.Errorf("status=%s listed %+v, want %s", status, body.Miners, want)
+		}
+		if got, _ := minerStatusSince(clients[want], cfg); !strings.EqualFold(string(got), status) {
+			t.Errorf("%s is %s on its own, listed as %s", want, got, status)
+		}
+	}
+}
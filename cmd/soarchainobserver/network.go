@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/metrics"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxLeaderboardLimit caps the number of leaderboard entries per request
+const maxLeaderboardLimit = 500
+
+// inactiveWalletsQuery selects earnings addresses that only belong to
+// soft-deleted clients. Earnings are stored under the solana address, or
+// the core address when the client has none.
+const inactiveWalletsQuery = `
+        SELECT COALESCE(NULLIF(solana_address, ''), address) FROM clients WHERE deleted_at IS NOT NULL
+        EXCEPT
+        SELECT COALESCE(NULLIF(solana_address, ''), address) FROM clients WHERE deleted_at IS NULL
+    `
+
+// activeMinersPeriods are the windows exported as the active_miners gauge
+var activeMinersPeriods = []string{"1h", "24h", "7d"}
+
+// activeMinersInterval is how often the active_miners gauge is refreshed
+const activeMinersInterval = time.Minute
+
+// countActiveMiners returns the number of distinct wallets with earnings
+// between start and end.
+func countActiveMiners(db *gorm.DB, start, end time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&models.ClientEarning{}).
+		Where("timestamp BETWEEN ? AND ?", start, end).
+		Distinct("client_address").
+		Count(&count).Error
+	return count, err
+}
+
+// getActiveMiners handles GET /api/v1/network/active-miners?period=24h
+// It counts the distinct wallets that earned within the period. With
+// period=epoch, identifier=day|week selects whose current epoch.
+func getActiveMiners(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	window, err := parsePeriodWindow(c, "24h")
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	count, err := countActiveMiners(db, window.Start, window.End)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+	asOf, err := dataAsOf(c, db)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"period":       window.Period,
+		"align":        window.Align,
+		"startTime":    window.Start.Format(time.RFC3339),
+		"endTime":      window.End.Format(time.RFC3339),
+		"activeMiners": count,
+		"dataAsOf":     asOf,
+	})
+}
+
+// getIngestionLag handles GET /api/v1/network/ingestion-lag
+// It reports the timestamp of the latest stored earning and how far it is
+// behind now, both null until anything has been stored. Unlike block lag
+// this also catches earnings that are received but not stored.
+func getIngestionLag(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	latest, err := c.MustGet("freshness").(*dataFreshness).get(db)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	var lagSeconds interface{}
+	if lag, ok := ingestionLag(latest, now); ok {
+		lagSeconds = lag.Seconds()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"latestEarning":       formatOptionalTime(latest),
+		"ingestionLagSeconds": lagSeconds,
+		"checkedAt":           now.Format(time.RFC3339),
+	})
+}
+
+// updateActiveMinersGauge refreshes the active_miners gauge for every
+// period in activeMinersPeriods, once right away and then every
+// activeMinersInterval until ctx is cancelled.
+func updateActiveMinersGauge(ctx context.Context, db *gorm.DB, logger *log.Logger) {
+	ticker := time.NewTicker(activeMinersInterval)
+	defer ticker.Stop()
+
+	for {
+		end := time.Now().UTC()
+		for _, period := range activeMinersPeriods {
+			duration, err := utils.ParsePeriod(period)
+			if err != nil {
+				continue
+			}
+			count, err := countActiveMiners(db.WithContext(ctx), end.Add(-duration), end)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Printf("Error counting active miners over %s: %v", period, err)
+				}
+				continue
+			}
+			metrics.ActiveMiners.WithLabelValues(period).Set(float64(count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// maxEpochTotalsSpan caps how many epochs one epoch-totals request covers
+const maxEpochTotalsSpan = 1000
+
+// getEpochTotals handles GET /api/v1/network/epoch-totals?fromEpoch=&toEpoch=&identifier=day
+// It returns the earnings distributed and the number of participating
+// wallets for every epoch in the range. Epochs without earnings inside the
+// range are reported with zero totals. A number reused after a chain reset
+// is reported once per epoch, oldest first.
+func getEpochTotals(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	epochs, err := parseEpochRange(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+	if epochs.From != nil && epochs.To != nil && *epochs.To-*epochs.From >= maxEpochTotalsSpan {
+		respondParamError(c, &invalidParamError{
+			Code:    "epoch_range_too_wide",
+			Message: fmt.Sprintf("Epoch range must not span more than %d epochs", maxEpochTotalsSpan),
+		})
+		return
+	}
+
+	identifier, err := parseEpochIdentifier(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	var rows []struct {
+		EpochNumber   int64
+		StartTime     time.Time
+		EndTime       time.Time
+		TotalEarnings int64
+		Participants  int64
+	}
+	err = epochs.apply(epochRewards(db).Where("identifier = ?", identifier)).
+		Select("epoch_number, start_time, MAX(end_time) AS end_time, " +
+			"SUM(total_earnings) AS total_earnings, COUNT(DISTINCT client_address) AS participants").
+		Group("epoch_number, start_time").
+		Order("epoch_number ASC, start_time ASC").
+		Scan(&rows).Error
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	asOf, err := dataAsOf(c, db)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	// Zero-fill between the requested bounds, or the epochs found for an
+	// open bound
+	var first, last int64
+	switch {
+	case epochs.From != nil:
+		first = *epochs.From
+	case len(rows) > 0:
+		first = rows[0].EpochNumber
+	}
+	switch {
+	case epochs.To != nil:
+		last = *epochs.To
+	case len(rows) > 0:
+		last = rows[len(rows)-1].EpochNumber
+	}
+	if len(rows) == 0 && (epochs.From == nil || epochs.To == nil) {
+		first, last = 0, -1
+	}
+	// With an open bound keep the most recent epochs
+	if last-first >= maxEpochTotalsSpan {
+		first = last - maxEpochTotalsSpan + 1
+	}
+
+	totals := make([]gin.H, 0, last-first+1)
+	next := 0
+	for epoch := first; epoch <= last; epoch++ {
+		for next < len(rows) && rows[next].EpochNumber < epoch {
+			next++
+		}
+		if next < len(rows) && rows[next].EpochNumber == epoch {
+			for ; next < len(rows) && rows[next].EpochNumber == epoch; next++ {
+				row := rows[next]
+				totals = append(totals, gin.H{
+					"epochNumber":   epoch,
+					"startTime":     row.StartTime.Format(time.RFC3339),
+					"endTime":       row.EndTime.Format(time.RFC3339),
+					"totalEarnings": amountFormat(c).amount(row.TotalEarnings),
+					"participants":  row.Participants,
+				})
+			}
+			continue
+		}
+		totals = append(totals, gin.H{
+			"epochNumber":   epoch,
+			"startTime":     nil,
+			"endTime":       nil,
+			"totalEarnings": amountFormat(c).amount(0),
+			"participants":  0,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identifier":  identifier,
+		"tokenSymbol": tokenSymbol(c),
+		"epochs":      totals,
+		"dataAsOf":    asOf,
+	})
+}
+
+// getLeaderboard handles GET /api/v1/network/leaderboard?period=24h&limit=50&minAmount=
+// It ranks wallets by their earnings in the period, leaving out wallets that
+// earned less than minAmount tokens and, unless includeInactive=true,
+// wallets of inactive (soft-deleted) clients. With period=epoch,
+// identifier=day|week selects whose current epoch.
+func getLeaderboard(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	window, err := parsePeriodWindow(c, "24h")
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	limit, err := parseLimit(c, 50, maxLeaderboardLimit)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	minAmount, err := parseMinAmount(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	includeInactive, err := parseIncludeInactive(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	asOf, err := dataAsOf(c, db)
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	query := `
+        SELECT client_address, SUM(earnings) AS total_earnings
+        FROM client_earnings
+        WHERE timestamp BETWEEN ? AND ?
+          AND (? OR client_address NOT IN (` + inactiveWalletsQuery + `))
+        GROUP BY client_address
+        HAVING SUM(earnings) >= ?
+        ORDER BY total_earnings DESC
+        LIMIT ?
+    `
+	// The query is bound to the request so a client going away mid-stream
+	// cancels it and returns the connection to the pool
+	rows, err := db.WithContext(c.Request.Context()).
+		Raw(query, window.Start, window.End, includeInactive, minAmount, limit).
+		Rows()
+	if err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+	defer rows.Close()
+
+	streamJSONList(c, gin.H{
+		"period":      window.Period,
+		"align":       window.Align,
+		"startTime":   window.Start.Format(time.RFC3339),
+		"endTime":     window.End.Format(time.RFC3339),
+		"tokenSymbol": tokenSymbol(c),
+		"dataAsOf":    asOf,
+	}, "entries", rows, func(rank int) (interface{}, error) {
+		var row struct {
+			ClientAddress string
+			TotalEarnings int64
+		}
+		if err := db.ScanRows(rows, &row); err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"rank":          rank,
+			"wallet":        row.ClientAddress,
+			"totalEarnings": amountFormat(c).amount(row.TotalEarnings),
+		}, nil
+	})
+}
+
+// getMinerRank handles GET /api/v1/miner/rank?wallet=&period=24h
+// It returns the wallet's rank by earnings in the period among the wallets
+// the leaderboard ranks, and how many wallets that is. Wallets with equal
+// earnings share a rank.
+func getMinerRank(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+
+	wallet, err := parseWallet(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	window, err := parsePeriodWindow(c, "24h")
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	includeInactive, err := parseIncludeInactive(c)
+	if err != nil {
+		respondParamError(c, err)
+		return
+	}
+
+	var rows []struct {
+		Rank          int64
+		RankedMiners  int64
+		TotalEarnings int64
+	}
+	query := `
+        WITH totals AS (
+            SELECT client_address, SUM(earnings) AS total_earnings
+            FROM client_earnings
+            WHERE timestamp BETWEEN ? AND ?
+              AND (? OR client_address NOT IN (` + inactiveWalletsQuery + `))
+            GROUP BY client_address
+        ), ranked AS (
+            SELECT client_address, total_earnings,
+                   RANK() OVER (ORDER BY total_earnings DESC) AS rank,
+                   COUNT(*) OVER () AS ranked_miners
+            FROM totals
+        )
+        SELECT rank, ranked_miners, total_earnings
+        FROM ranked
+        WHERE client_address = ?
+    `
+	if err := db.Raw(query, window.Start, window.End, includeInactive, wallet).Scan(&rows).Error; err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+	if len(rows) == 0 {
+		respondError(c, http.StatusNotFound, "No earnings for wallet in period")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"wallet":        wallet,
+		"rank":          rows[0].Rank,
+		"rankedMiners":  rows[0].RankedMiners,
+		"totalEarnings": amountFormat(c).amount(rows[0].TotalEarnings),
+		"tokenSymbol":   tokenSymbol(c),
+		"period":        window.Period,
+		"align":         window.Align,
+		"startTime":     window.Start.Format(time.RFC3339),
+		"endTime":       window.End.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+// TestEpochTotalsPerIdentifier aggregates the same earnings stored as day
+// and week epochs and expects each identifier totalled on its own.
+func TestEpochTotalsPerIdentifier(t *testing.T) {
+	cfg := loadTestConfig(t, `{"epoch_identifiers": ["day", "week"]}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return monday.AddDate(0, 0, n) }
+	epochs := []models.EpochEarnings{
+		// alice earns 1 on days 1 and 2, bob 2 on day 2
+		{ClientAddress: "SoLalice", Identifier: "day", EpochNumber: 1, StartTime: day(0), EndTime: day(1), TotalEarnings: 1000000},
+		{ClientAddress: "SoLalice", Identifier: "day", EpochNumber: 2, StartTime: day(1), EndTime: day(2), TotalEarnings: 1000000},
+		{ClientAddress: "SoLbob", Identifier: "day", EpochNumber: 2, StartTime: day(1), EndTime: day(2), TotalEarnings: 2000000},
+		// the same earnings in week 1
+		{ClientAddress: "SoLalice", Identifier: "week", EpochNumber: 1, StartTime: day(0), EndTime: day(7), TotalEarnings: 2000000},
+		{ClientAddress: "SoLbob", Identifier: "week", EpochNumber: 1, StartTime: day(0), EndTime: day(7), TotalEarnings: 2000000},
+	}
+	if err := db.Create(&epochs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	type total struct {
+		epoch        float64
+		earnings     float64
+		participants float64
+	}
+	tests := []struct {
+		query      string
+		identifier string
+		want       []total
+	}{
+		{"", "day", []total{{1, 1, 1}, {2, 3, 2}}}, // day by default
+		{"?identifier=day", "day", []total{{1, 1, 1}, {2, 3, 2}}},
+		{"?identifier=week", "week", []total{{1, 4, 2}}},
+	}
+	for _, tt := range tests {
+		body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/network/epoch-totals"+tt.query, ""), http.StatusOK)
+		if body["identifier"] != tt.identifier {
+			t.Errorf("%s: identifier %v, want %s", tt.query, body["identifier"], tt.identifier)
+		}
+		got := body["epochs"].([]interface{})
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: epochs %v, want %v", tt.query, got, tt.want)
+			continue
+		}
+		for i, raw := range got {
+			epoch := raw.(map[string]interface{})
+			if epoch["epochNumber"] != tt.want[i].epoch || epoch["totalEarnings"] != tt.want[i].earnings ||
+				epoch["participants"] != tt.want[i].participants {
+				t.Errorf("%s: epoch %v, want %+v", tt.query, epoch, tt.want[i])
+			}
+		}
+	}
+
+	// An identifier without epochs totals nothing; a malformed one is rejected
+	body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/network/epoch-totals?identifier=month", ""), http.StatusOK)
+	if got := body["epochs"].([]interface{}); len(got) != 0 {
+		t.Errorf("identifier=month: epochs %v, want none", got)
+	}
+	if w := serve(router, http.MethodGet, "/api/v1/network/epoch-totals?identifier=Week!", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("identifier=Week!: status %d, want 400", w.Code)
+	}
+}
+
+func TestEpochTotalsKeepReusedNumbersApart(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	oldStart := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	newStart := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	epochs := []models.EpochEarnings{
+		{ClientAddress: "SoLalice", Identifier: "day", EpochNumber: 3, StartTime: oldStart, EndTime: oldStart.AddDate(0, 0, 1), TotalEarnings: 1000000},
+		{ClientAddress: "SoLbob", Identifier: "day", EpochNumber: 3, StartTime: oldStart, EndTime: oldStart.AddDate(0, 0, 1), TotalEarnings: 1000000},
+		// the reset chain reuses epoch 3
+		{ClientAddress: "SoLalice", Identifier: "day", EpochNumber: 3, StartTime: newStart, EndTime: newStart.AddDate(0, 0, 1), TotalEarnings: 5000000},
+		{ClientAddress: "SoLalice", Identifier: "day", EpochNumber: 4, StartTime: newStart.AddDate(0, 0, 1), EndTime: newStart.AddDate(0, 0, 2), TotalEarnings: 1000000},
+	}
+	if err := db.Create(&epochs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := decodeJSON(t, serve(router, http.MethodGet, "/api/v1/network/epoch-totals?fromEpoch=2&toEpoch=4", ""), http.StatusOK)
+	want := []struct {
+		epoch        float64
+		start        interface{}
+		earnings     float64
+		participants float64
+	}{
+		{2, nil, 0, 0},
+		{3, oldStart.Format(time.RFC3339), 2, 2},
+		{3, newStart.Format(time.RFC3339), 5, 1},
+		{4, newStart.AddDate(0, 0, 1).Format(time.RFC3339), 1, 1},
+	}
+	got := body["epochs"].([]interface{})
+	if len(got) != len(want) {
+		t.Fatalf("epochs %v, want %d entries", got, len(want))
+	}
+	for i, raw := range got {
+		epoch := raw.(map[string]interface{})
+		if epoch["epochNumber"] != want[i].epoch || epoch["startTime"] != want[i].start ||
+			epoch["totalEarnings"] != want[i].earnings || epoch["participants"] != want[i].participants {
+			t.Errorf("entry %d = %v, want %+v", i, epoch, want[i])
+		}
+	}
+}
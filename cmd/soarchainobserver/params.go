@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// coreAddressPattern matches a bech32 soar core address (20 or 32 byte
+// payload plus checksum)
+var coreAddressPattern = regexp.MustCompile(`^soar1([02-9ac-hj-np-z]{38}|[02-9ac-hj-np-z]{58})$`)
+
+// solanaAddressPattern matches a base58-encoded 32 byte solana public key
+var solanaAddressPattern = regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32,44}$`)
+
+// parseWallet reads the required wallet query param, which must be a solana
+// address unless the config relaxes the check.
+func parseWallet(c *gin.Context) (string, error) {
+	wallet := c.Query("wallet")
+	if wallet == "" {
+		return "", fmt.Errorf("missing 'wallet' query param")
+	}
+	cfg := c.MustGet("config").(*config.Config)
+	if !cfg.AllowInvalidWallets && !solanaAddressPattern.MatchString(wallet) {
+		return "", fmt.Errorf("invalid 'wallet' query param: %q is not a solana address", wallet)
+	}
+	return wallet, nil
+}
+
+// parseEpochIdentifier reads the identifier query param selecting which
+// epochs ("day", "week") to report, defaulting to "day".
+func parseEpochIdentifier(c *gin.Context) (string, error) {
+	identifier := c.DefaultQuery("identifier", "day")
+	if !config.ValidEpochIdentifier(identifier) {
+		return "", fmt.Errorf("invalid 'identifier' query param")
+	}
+	return identifier, nil
+}
+
+// parseTrackedIdentifier is parseEpochIdentifier restricted to the epoch
+// identifiers the observer tracks, for params resolved from the epoch info.
+func parseTrackedIdentifier(c *gin.Context) (string, error) {
+	identifier, err := parseEpochIdentifier(c)
+	if err != nil {
+		return "", err
+	}
+	cfg := c.MustGet("config").(*config.Config)
+	for _, tracked := range cfg.EpochIdentifiers {
+		if identifier == tracked {
+			return identifier, nil
+		}
+	}
+	return "", &invalidParamError{
+		Code:    "untracked_identifier",
+		Message: fmt.Sprintf("Epoch identifier %q isn't tracked, expected one of %v", identifier, cfg.EpochIdentifiers),
+	}
+}
+
+// epochRange is an optional, inclusive epoch number filter. A nil bound is open.
+type epochRange struct {
+	From *int64
+	To   *int64
+}
+
+// parseEpochRange reads the fromEpoch/toEpoch query params.
+func parseEpochRange(c *gin.Context) (epochRange, error) {
+	var r epochRange
+	for _, p := range []struct {
+		name string
+		dst  **int64
+	}{{"fromEpoch", &r.From}, {"toEpoch", &r.To}} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 0 {
+			return r, fmt.Errorf("invalid '%s' query param", p.name)
+		}
+		*p.dst = &v
+	}
+	if r.From != nil && r.To != nil && *r.From > *r.To {
+		return r, &invalidParamError{
+			Code:    "inverted_epoch_range",
+			Message: fmt.Sprintf("'fromEpoch' (%d) must not be greater than 'toEpoch' (%d)", *r.From, *r.To),
+		}
+	}
+	return r, nil
+}
+
+// apply restricts query to the range's epoch numbers.
+func (r epochRange) apply(query *gorm.DB) *gorm.DB {
+	switch {
+	case r.From != nil && r.To != nil:
+		return query.Where("epoch_number BETWEEN ? AND ?", *r.From, *r.To)
+	case r.From != nil:
+		return query.Where("epoch_number >= ?", *r.From)
+	case r.To != nil:
+		return query.Where("epoch_number <= ?", *r.To)
+	}
+	return query
+}
+
+// timeRange is an optional, inclusive timestamp filter. A nil bound is open.
+type timeRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// parseTimeRange reads the from/to query params, given as RFC 3339
+// timestamps.
+func parseTimeRange(c *gin.Context) (timeRange, error) {
+	var r timeRange
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &r.From}, {"to", &r.To}} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		v, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return r, fmt.Errorf("invalid '%s' query param: must be an RFC 3339 timestamp", p.name)
+		}
+		v = v.UTC()
+		*p.dst = &v
+	}
+	if r.From != nil && r.To != nil && r.From.After(*r.To) {
+		return r, &invalidParamError{
+			Code:    "inverted_time_range",
+			Message: "'from' must not be after 'to'",
+		}
+	}
+	return r, nil
+}
+
+// apply restricts query to rows whose timestamp is within the range.
+func (r timeRange) apply(query *gorm.DB) *gorm.DB {
+	if r.From != nil {
+		query = query.Where("timestamp >= ?", *r.From)
+	}
+	if r.To != nil {
+		query = query.Where("timestamp <= ?", *r.To)
+	}
+	return query
+}
+
+// Page sizes of the paginated listings
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// pagination is the page of a listing selected by the page and pageSize
+// query params
+type pagination struct {
+	Page     int // 1-based
+	PageSize int
+}
+
+// parsePagination reads the 1-based page and pageSize query params shared
+// by the paginated listings. Missing params default to the first page of
+// defaultPageSize; page sizes above maxPageSize are capped, and values that
+// aren't positive integers are rejected.
+func parsePagination(c *gin.Context) (pagination, error) {
+	p := pagination{Page: 1, PageSize: defaultPageSize}
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{{"page", &p.Page}, {"pageSize", &p.PageSize}} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v <= 0 {
+			return p, fmt.Errorf("invalid '%s' query param: must be a positive integer", param.name)
+		}
+		*param.dst = v
+	}
+	if p.PageSize > maxPageSize {
+		p.PageSize = maxPageSize
+	}
+	// keeps the offset from overflowing
+	if p.Page-1 > math.MaxInt32/p.PageSize {
+		return p, &invalidParamError{Code: "page_out_of_range", Message: "'page' is out of range"}
+	}
+	return p, nil
+}
+
+// apply restricts query to the rows of the page.
+func (p pagination) apply(query *gorm.DB) *gorm.DB {
+	return query.Offset((p.Page - 1) * p.PageSize).Limit(p.PageSize)
+}
+
+// parseLimit reads the limit query param of the endpoints returning the
+// latest n rows, defaulting to defaultLimit. Values above maxLimit are
+// capped, and values that aren't positive integers are rejected.
+func parseLimit(c *gin.Context, defaultLimit, maxLimit int) (int, error) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid 'limit' query param: must be a positive integer")
+	}
+	return min(limit, maxLimit), nil
+}
+
+// parseIncludeInactive reads the includeInactive query param that makes
+// listings include soft-deleted (inactive) clients.
+func parseIncludeInactive(c *gin.Context) (bool, error) {
+	raw := c.Query("includeInactive")
+	if raw == "" {
+		return false, nil
+	}
+	includeInactive, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid 'includeInactive' query param")
+	}
+	return includeInactive, nil
+}
+
+// parseMinAmount reads the optional minAmount query param, given in token
+// units, and returns it in micro-units (0 when absent).
+func parseMinAmount(c *gin.Context) (int64, error) {
+	raw := c.Query("minAmount")
+	if raw == "" {
+		return 0, nil
+	}
+	amount, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("invalid 'minAmount' query param")
+	}
+	if amount < 0 {
+		return 0, &invalidParamError{Code: "negative_amount", Message: "'minAmount' must not be negative"}
+	}
+	return int64(math.Round(amountFormat(c).micro(amount))), nil
+}
+
+// Window alignments selected by the align query param
+const (
+	alignRolling  = "rolling"  // the period ending now
+	alignCalendar = "calendar" // from the calendar boundary the period starts on
+	alignEpoch    = "epoch"    // reported for period=epoch, which ignores align
+)
+
+// periodEpoch is the period value selecting the current primary epoch so far
+const periodEpoch = "epoch"
+
+// periodWindow is the time window selected by the period and align params
+type periodWindow struct {
+	Period string
+	Align  string
+	Start  time.Time
+	End    time.Time
+}
+
+// Duration is the length of the window, shorter than the period for
+// calendar-aligned windows.
+func (w periodWindow) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+// parsePeriodWindow reads the period query param (defaulting to
+// defaultPeriod) and the align param. With align=rolling (the default) the
+// window is the period ending now. With align=calendar it starts on the
+// calendar boundary: "24h" means today, "7d" this week (from Monday) and
+// "2d" yesterday and today, all UTC; other periods snap to multiples of
+// the period, e.g. "1h" is the current hour.
+//
+// period=epoch selects the time since the start of the current epoch of
+// the identifier query param ("day", "week"), or of the primary epoch when
+// it is absent, taken from the cached epoch info; it fails with
+// errEpochUnavailable until the epoch has been fetched.
+func parsePeriodWindow(c *gin.Context, defaultPeriod string) (periodWindow, error) {
+	w := periodWindow{Period: c.DefaultQuery("period", defaultPeriod), Align: c.DefaultQuery("align", alignRolling)}
+	if w.Period == periodEpoch {
+		cfg := c.MustGet("config").(*config.Config)
+		blockReader := c.MustGet("blockReader").(*blockchain.BlockReader)
+		identifier := cfg.EpochIdentifiers[0]
+		if c.Query("identifier") != "" {
+			var err error
+			if identifier, err = parseTrackedIdentifier(c); err != nil {
+				return w, err
+			}
+		}
+		epoch, ok := blockReader.CachedEpoch(identifier)
+		if !ok {
+			return w, errEpochUnavailable
+		}
+		w.Align = alignEpoch
+		w.Start = epoch.CurrentEpochStart
+		w.End = time.Now().UTC()
+		return w, nil
+	}
+
+	duration, err := utils.ParsePeriod(w.Period)
+	if err != nil {
+		return w, fmt.Errorf("invalid period format: %v", err)
+	}
+
+	w.End = time.Now().UTC()
+	switch w.Align {
+	case alignRolling:
+		w.Start = w.End.Add(-duration)
+	case alignCalendar:
+		w.Start = calendarStart(w.End, duration)
+	default:
+		return w, fmt.Errorf("invalid 'align' query param, expected %q or %q", alignRolling, alignCalendar)
+	}
+	return w, nil
+}
+
+// calendarStart returns the start of the calendar-aligned window of the
+// given length containing now.
+func calendarStart(now time.Time, duration time.Duration) time.Time {
+	const day = 24 * time.Hour
+	const week = 7 * day
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case duration%week == 0:
+		monday := midnight.AddDate(0, 0, -((int(midnight.Weekday()) + 6) % 7))
+		return monday.Add(-(duration - week))
+	case duration%day == 0:
+		return midnight.Add(-(duration - day))
+	default:
+		return now.Truncate(duration)
+	}
+}
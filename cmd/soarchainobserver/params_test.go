@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// paramContext returns a gin context for a GET of target, with cfg and its
+// amount formatting set as the handlers expect.
+func paramContext(target string, cfg *config.Config) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", target, nil)
+	c.Set("config", cfg)
+	c.Set("amounts", newAmountFormatting(cfg))
+	return c
+}
+
+func TestParseEpochIdentifier(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"", "day", false},
+		{"?identifier=week", "week", false},
+		{"?identifier=epoch_2", "epoch_2", false},
+		{"?identifier=Week", "", true},
+		{"?identifier=2day", "", true},
+		{"?identifier=day%2F..%2Fweek", "", true},
+		{"?identifier=", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseEpochIdentifier(paramContext("/"+tt.query, cfg))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseEpochIdentifier(%q) = %q, %v; want %q (error %v)", tt.query, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	tests := []struct {
+		query    string
+		want     pagination
+		wantErr  string
+		wantCode string // code of an invalidParamError
+	}{
+		{query: "", want: pagination{Page: 1, PageSize: defaultPageSize}},
+		{query: "page=3&pageSize=20", want: pagination{Page: 3, PageSize: 20}},
+		{query: "pageSize=100000", want: pagination{Page: 1, PageSize: maxPageSize}},
+		{query: "page=0", wantErr: "invalid 'page' query param: must be a positive integer"},
+		{query: "pageSize=0", wantErr: "invalid 'pageSize' query param: must be a positive integer"},
+		{query: "page=-2", wantErr: "invalid 'page' query param: must be a positive integer"},
+		{query: "pageSize=-10", wantErr: "invalid 'pageSize' query param: must be a positive integer"},
+		{query: "page=two", wantErr: "invalid 'page' query param: must be a positive integer"},
+		{query: "pageSize=1.5", wantErr: "invalid 'pageSize' query param: must be a positive integer"},
+		{query: "page=99999999999999999999", wantErr: "invalid 'page' query param: must be a positive integer"},
+		{query: "page=4294968&pageSize=500", want: pagination{Page: 4294968, PageSize: 500}},
+		{query: "page=4294969&pageSize=500", wantErr: "'page' is out of range", wantCode: "page_out_of_range"},
+		{query: "page=2147483647&pageSize=100000", wantErr: "'page' is out of range", wantCode: "page_out_of_range"},
+	}
+	for _, tt := range tests {
+		got, err := parsePagination(paramContext("/?"+tt.query, cfg))
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%q: error %v, want %q", tt.query, err, tt.wantErr)
+				continue
+			}
+			code := ""
+			var invalid *invalidParamError
+			if errors.As(err, &invalid) {
+				code = invalid.Code
+			}
+			if code != tt.wantCode {
+				t.Errorf("%q: code %q, want %q", tt.query, code, tt.wantCode)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %+v, %v; want %+v", tt.query, got, err, tt.want)
+		}
+		if offset := (got.Page - 1) * got.PageSize; offset < 0 || offset > math.MaxInt32 {
+			t.Errorf("%q: offset %d out of range", tt.query, offset)
+		}
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	tests := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{query: "", want: 7},
+		{query: "limit=3", want: 3},
+		{query: "limit=100", want: 100},
+		{query: "limit=101", want: 100},
+		{query: "limit=99999999", want: 100},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=-1", wantErr: true},
+		{query: "limit=abc", wantErr: true},
+		{query: "limit=2.5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseLimit(paramContext("/?"+tt.query, cfg), 7, 100)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: got %d, want an error", tt.query, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: got %d, %v; want %d", tt.query, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+// TestCompositeResponsesSurviveAFailingPart drops the table behind one part
+// of the dashboard and summary responses and expects the other parts.
+func TestCompositeResponsesSurviveAFailingPart(t *testing.T) {
+	tests := []struct {
+		path        string
+		drop        interface{}
+		wantMissing string
+		wantPresent []string
+	}{
+		{"/api/v1/miner/dashboard", &models.EpochEarnings{}, "latestRewards",
+			[]string{"status", "totalLifetimeEarnings", "tokenSymbol"}},
+		{"/api/v1/miner/dashboard", &models.PriceHistory{}, "usdValue",
+			[]string{"status", "latestRewards", "totalLifetimeEarnings"}},
+		{"/api/v1/miner/summary", &models.ClientEarning{}, "todayEarnings",
+			[]string{"status", "lastSeen", "lifetimeEarnings", "currentEpoch"}},
+	}
+	for _, tt := range tests {
+		cfg := loadTestConfig(t, `{}`)
+		db := openTestDB(t, cfg)
+		router := setupRouter(routerDeps{
+			DB:          db,
+			BlockReader: &blockchain.BlockReader{},
+			Config:      cfg,
+			Logger:      quietLogger,
+			Warmup:      newWarmupState(cfg),
+		})
+		if err := db.Create(&models.Client{Address: "soar1alice", SolanaAddress: testWallet,
+			TotalLifetimeEarnings: 5000000, LastChallengeTime: time.Now().UTC()}).Error; err != nil {
+			t.Fatal(err)
+		}
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		if err := db.Create(&models.EpochEarnings{ClientAddress: testWallet, Identifier: "day", EpochNumber: 1,
+			StartTime: start, EndTime: start.Add(24 * time.Hour), TotalEarnings: 1000000}).Error; err != nil {
+			t.Fatal(err)
+		}
+		path := tt.path + "?wallet=" + testWallet
+
+		// Nothing is missing while the database works
+		body := decodeJSON(t, serve(router, http.MethodGet, path, ""), http.StatusOK)
+		if warnings, ok := body["warnings"].([]interface{}); !ok || len(warnings) != 0 {
+			t.Errorf("%s: warnings %v before the failure, want []", tt.path, body["warnings"])
+		}
+
+		if err := db.Migrator().DropTable(tt.drop); err != nil {
+			t.Fatal(err)
+		}
+		body = decodeJSON(t, serve(router, http.MethodGet, path, ""), http.StatusOK)
+		if want := []interface{}{tt.wantMissing}; !reflect.DeepEqual(body["warnings"], want) {
+			t.Errorf("%s without %T: warnings %v, want %v", tt.path, tt.drop, body["warnings"], want)
+		}
+		for _, field := range tt.wantPresent {
+			if body[field] == nil {
+				t.Errorf("%s without %T: %s missing from %v", tt.path, tt.drop, field, body)
+			}
+		}
+	}
+}
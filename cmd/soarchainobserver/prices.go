@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// priceBook is the recorded price history of one token, oldest first.
+type priceBook []models.PriceHistory
+
+// loadPrices returns the prices of symbol needed to value times between
+// from and to: those recorded in between, the last one before, and the
+// latest one, which priceBook.at falls back to for times before the first
+// recorded price.
+func loadPrices(db *gorm.DB, symbol string, from, to time.Time) (priceBook, error) {
+	var prices priceBook
+	if err := db.Where("symbol = ? AND timestamp > ? AND timestamp <= ?", symbol, from, to).
+		Order("timestamp ASC, id ASC").
+		Find(&prices).Error; err != nil {
+		return nil, err
+	}
+
+	var before, latest []models.PriceHistory
+	if err := db.Where("symbol = ? AND timestamp <= ?", symbol, from).
+		Order("timestamp DESC, id DESC").
+		Limit(1).
+		Find(&before).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("symbol = ? AND timestamp > ?", symbol, to).
+		Order("timestamp DESC, id DESC").
+		Limit(1).
+		Find(&latest).Error; err != nil {
+		return nil, err
+	}
+	prices = append(before, prices...)
+	return append(prices, latest...), nil
+}
+
+// loadEpochPrices returns the prices needed to value epochs at their end.
+func loadEpochPrices(db *gorm.DB, symbol string, epochs []models.EpochEarnings) (priceBook, error) {
+	if len(epochs) == 0 {
+		return nil, nil
+	}
+	from, to := epochs[0].EndTime, epochs[0].EndTime
+	for _, e := range epochs[1:] {
+		if e.EndTime.Before(from) {
+			from = e.EndTime
+		}
+		if e.EndTime.After(to) {
+			to = e.EndTime
+		}
+	}
+	return loadPrices(db, symbol, from, to)
+}
+
+// at returns the last price recorded at or before t. Without one it falls
+// back to the latest price, the closest there is to a current price, and
+// reports false when no price was ever recorded.
+func (b priceBook) at(t time.Time) (float64, bool) {
+	if len(b) == 0 {
+		return 0, false
+	}
+	i := sort.Search(len(b), func(i int) bool { return b[i].Timestamp.After(t) })
+	if i == 0 {
+		return b[len(b)-1].USD, true
+	}
+	return b[i-1].USD, true
+}
+
+// usdValue values micro tokens, converted with format, at the price at t, or
+// nil when there is no price.
+func (b priceBook) usdValue(format amountFormatting, micro int64, t time.Time) interface{} {
+	price, ok := b.at(t)
+	if !ok {
+		return nil
+	}
+	return math.Round(format.tokens(float64(micro))*price*100) / 100
+}
+
+// recordPrice handles POST /api/v1/admin/prices with {"timestamp": ..., "usd": ...}
+// It records the price of the configured network's token, for prices the
+// price provider (price_provider_url) missed or history from before it was
+// configured. The timestamp defaults to now.
+func recordPrice(c *gin.Context) {
+	db := c.MustGet("db").(*gorm.DB)
+	cfg := c.MustGet("config").(*config.Config)
+
+	var body struct {
+		Timestamp time.Time `json:"timestamp"`
+		USD       *float64  `json:"usd"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid price: %v", err))
+		return
+	}
+	if body.USD == nil || *body.USD < 0 || math.IsInf(*body.USD, 0) {
+		respondError(c, http.StatusBadRequest, "'usd' must be a non-negative number")
+		return
+	}
+	if body.Timestamp.IsZero() {
+		body.Timestamp = time.Now()
+	}
+
+	price := models.PriceHistory{
+		Symbol:    cfg.TokenSymbol(),
+		Timestamp: body.Timestamp.UTC(),
+		USD:       *body.USD,
+	}
+	if err := db.Create(&price).Error; err != nil {
+		respondDBError(c, db, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"symbol":    price.Symbol,
+		"timestamp": price.Timestamp.Format(time.RFC3339),
+		"usd":       price.USD,
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestPriceBookAt(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	book := priceBook{
+		{Timestamp: base, USD: 1},
+		{Timestamp: base.Add(24 * time.Hour), USD: 2},
+		{Timestamp: base.Add(48 * time.Hour), USD: 3},
+	}
+	tests := []struct {
+		name string
+		t    time.Time
+		want float64
+	}{
+		{"at a recorded price", base.Add(24 * time.Hour), 2},
+		{"between prices", base.Add(36 * time.Hour), 2},
+		{"after the last price", base.Add(72 * time.Hour), 3},
+		{"before any price falls back to the latest", base.Add(-time.Hour), 3},
+	}
+	for _, tt := range tests {
+		if got, ok := book.at(tt.t); !ok || got != tt.want {
+			t.Errorf("%s: at = %v, %v; want %v", tt.name, got, ok, tt.want)
+		}
+	}
+	if _, ok := (priceBook{}).at(base); ok {
+		t.Error("empty book reported a price")
+	}
+}
+
+func TestLoadPrices(t *testing.T) {
+	db := openTestDB(t, loadTestConfig(t, `{}`))
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day < 10; day++ {
+		if err := db.Create(&models.PriceHistory{Symbol: "SOAR", Timestamp: base.Add(time.Duration(day) * 24 * time.Hour), USD: float64(day)}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Create(&models.PriceHistory{Symbol: "OTHER", Timestamp: base.Add(84 * time.Hour), USD: 99}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Days 3-5 are in the window, day 2 is the last before it and day 9
+	// the latest
+	book, err := loadPrices(db, "SOAR", base.Add(60*time.Hour), base.Add(120*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []float64
+	for _, price := range book {
+		got = append(got, price.USD)
+	}
+	want := []float64{2, 3, 4, 5, 9}
+	if len(got) != len(want) {
+		t.Fatalf("loaded prices %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("loaded prices %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRewardsValuedAtHistoricalPrice(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	router := newTestRouter(t, db, cfg)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for epoch := int64(1); epoch <= 3; epoch++ {
+		start := base.Add(time.Duration(epoch-1) * 24 * time.Hour)
+		if err := db.Create(&models.EpochEarnings{
+			ClientAddress: testWallet, Identifier: "day", EpochNumber: epoch,
+			StartTime: start, EndTime: start.Add(24 * time.Hour), TotalEarnings: 2_000_000,
+		}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Prices recorded at the end of epochs 2 and 3, none before epoch 1 ended
+	for _, price := range []models.PriceHistory{
+		{Symbol: "SOAR", Timestamp: base.Add(48 * time.Hour), USD: 0.5},
+		{Symbol: "SOAR", Timestamp: base.Add(72 * time.Hour), USD: 0.75},
+	} {
+		if err := db.Create(&price).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := serve(router, http.MethodGet, "/api/v1/miner/all-rewards?wallet="+testWallet, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var rewards []map[string]interface{}
+	decodeJSONInto(t, w, &rewards)
+
+	// Epoch 1 ended before any price, so it is valued at the latest
+	want := map[float64]float64{1: 1.5, 2: 1, 3: 1.5}
+	if len(rewards) != 3 {
+		t.Fatalf("got %d epochs, want 3", len(rewards))
+	}
+	for _, reward := range rewards {
+		epoch := reward["epochNumber"].(float64)
+		if reward["usdValue"] != want[epoch] {
+			t.Errorf("epoch %v valued at %v USD, want %v", epoch, reward["usdValue"], want[epoch])
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerProfiling mounts the net/http/pprof handlers under /debug/pprof,
+// gated by the admin API key like the admin endpoints.
+func registerProfiling(router *gin.Engine, apiKey string) {
+	debug := router.Group("/debug/pprof", requireAdminKey(apiKey))
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		for _, profile := range []string{"allocs", "block", "goroutine", "heap", "mutex", "threadcreate"} {
+			debug.GET("/"+profile, gin.WrapH(pprof.Handler(profile)))
+		}
+	}
+}
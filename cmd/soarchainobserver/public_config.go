@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// getPublicConfig handles GET /api/v1/config
+// It returns the settings clients need to interpret responses: token
+// details, status thresholds and epochs. Fields are listed one by one so a
+// new config setting, possibly a secret, is never exposed by default.
+func getPublicConfig(c *gin.Context) {
+	cfg := c.MustGet("config").(*config.Config)
+
+	c.JSON(http.StatusOK, gin.H{
+		"network": cfg.Network,
+		"token": gin.H{
+			"symbol":           cfg.TokenSymbol(),
+			"denom":            cfg.ExpectedDenom,
+			"decimals":         cfg.TokenDecimals,
+			"amountDecimals":   cfg.DisplayDecimals(),
+			"amountsAsStrings": cfg.AmountsAsStrings,
+		},
+		"status": gin.H{
+			"degradedAfterSeconds": cfg.StatusDegradedAfter.Duration.Seconds(),
+			"downAfterSeconds":     cfg.StatusDownAfter.Duration.Seconds(),
+			"degradedEnabled":      !cfg.DisableDegradedStatus,
+		},
+		"epochs": gin.H{
+			"aggregation": cfg.EpochAggregation(),
+			"identifiers": cfg.EpochIdentifiers,
+		},
+		"expectedChallengeIntervalSeconds": cfg.ExpectedChallengeInterval.Duration.Seconds(),
+		"expectedChallengesPerDay":         cfg.ExpectedChallenges(24 * time.Hour),
+	})
+}
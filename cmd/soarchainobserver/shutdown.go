@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// subsystems tracks the named goroutines that must stop before the DB is
+// closed, so a shutdown that runs out of time can say which ones didn't.
+type subsystems struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	running map[string]bool
+}
+
+func newSubsystems() *subsystems {
+	return &subsystems{running: make(map[string]bool)}
+}
+
+// Go runs fn in a goroutine tracked under name.
+func (s *subsystems) Go(name string, fn func()) {
+	s.mu.Lock()
+	s.running[name] = true
+	s.mu.Unlock()
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, name)
+			s.mu.Unlock()
+		}()
+		fn()
+	}()
+}
+
+// WaitUntil waits for every subsystem to return or the deadline to pass,
+// and returns the names of the subsystems still running, sorted.
+func (s *subsystems) WaitUntil(deadline time.Time) []string {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.running))
+	for name := range s.running {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
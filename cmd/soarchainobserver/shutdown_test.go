@@ -0,0 +1,49 @@
+package main
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubsystemsWaitForEveryGoroutine(t *testing.T) {
+	workers := newSubsystems()
+	release := make(chan struct{})
+	var finished int32
+	for _, name := range []string{"API server", "block reader"} {
+		workers.Go(name, func() {
+			<-release
+			atomic.AddInt32(&finished, 1)
+		})
+	}
+
+	close(release)
+	if stuck := workers.WaitUntil(time.Now().Add(5 * time.Second)); stuck != nil {
+		t.Fatalf("still running: %v", stuck)
+	}
+	// WaitUntil only returns once both have returned, so the DB could be
+	// closed here
+	if n := atomic.LoadInt32(&finished); n != 2 {
+		t.Errorf("%d goroutines finished before WaitUntil returned, want 2", n)
+	}
+}
+
+func TestSubsystemsReportStuckGoroutines(t *testing.T) {
+	workers := newSubsystems()
+	release := make(chan struct{})
+	defer close(release)
+
+	workers.Go("block reader", func() { <-release })
+	workers.Go("API server", func() { <-release })
+	workers.Go("epoch archiver", func() {})
+
+	start := time.Now()
+	stuck := workers.WaitUntil(start.Add(50 * time.Millisecond))
+	if want := []string{"API server", "block reader"}; !reflect.DeepEqual(stuck, want) {
+		t.Errorf("stuck = %v, want %v", stuck, want)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("WaitUntil took %s past its deadline", elapsed)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+// statusCache is a small LRU of client lookups by solana wallet with a short
+// TTL, absorbing dashboards that poll /api/v1/miner/status. Unknown wallets
+// are cached too (as a nil client). It is safe for concurrent use.
+type statusCache struct {
+	ttl  time.Duration
+	size int
+
+	mu    sync.Mutex
+	order *list.List // most recently used first
+	items map[string]*list.Element
+}
+
+type statusCacheEntry struct {
+	wallet    string
+	client    *models.Client
+	expiresAt time.Time
+}
+
+func newStatusCache(ttl time.Duration, size int) *statusCache {
+	return &statusCache{
+		ttl:   ttl,
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+// get returns the cached client for wallet and whether there was a live entry.
+func (sc *statusCache) get(wallet string) (*models.Client, bool) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	elem, ok := sc.items[wallet]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*statusCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		sc.order.Remove(elem)
+		delete(sc.items, wallet)
+		return nil, false
+	}
+	sc.order.MoveToFront(elem)
+	return entry.client, true
+}
+
+// put caches client (nil for an unknown wallet), evicting the least recently
+// used entry when the cache is full.
+func (sc *statusCache) put(wallet string, client *models.Client) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	expiresAt := time.Now().Add(sc.ttl)
+	if elem, ok := sc.items[wallet]; ok {
+		entry := elem.Value.(*statusCacheEntry)
+		entry.client = client
+		entry.expiresAt = expiresAt
+		sc.order.MoveToFront(elem)
+		return
+	}
+
+	if sc.order.Len() >= sc.size {
+		oldest := sc.order.Back()
+		sc.order.Remove(oldest)
+		delete(sc.items, oldest.Value.(*statusCacheEntry).wallet)
+	}
+	sc.items[wallet] = sc.order.PushFront(&statusCacheEntry{
+		wallet:    wallet,
+		client:    client,
+		expiresAt: expiresAt,
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestStatusCacheGetPut(t *testing.T) {
+	cache := newStatusCache(time.Minute, 4)
+
+	if _, ok := cache.get("SoLa"); ok {
+		t.Fatal("empty cache reported a hit")
+	}
+
+	client := &models.Client{Address: "soar1a"}
+	cache.put("SoLa", client)
+	if got, ok := cache.get("SoLa"); !ok || got != client {
+		t.Fatalf("get = %v, %v; want the cached client", got, ok)
+	}
+
+	// Unknown wallets are cached as nil
+	cache.put("SoLunknown", nil)
+	if got, ok := cache.get("SoLunknown"); !ok || got != nil {
+		t.Fatalf("get unknown = %v, %v; want a nil hit", got, ok)
+	}
+
+	// put replaces the entry
+	replacement := &models.Client{Address: "soar1b"}
+	cache.put("SoLa", replacement)
+	if got, _ := cache.get("SoLa"); got != replacement {
+		t.Fatalf("get after put = %v, want the replacement", got)
+	}
+}
+
+func TestStatusCacheExpiry(t *testing.T) {
+	cache := newStatusCache(20*time.Millisecond, 4)
+	cache.put("SoLa", &models.Client{Address: "soar1a"})
+
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := cache.get("SoLa"); ok {
+		t.Fatal("expired entry reported a hit")
+	}
+	if n := cache.order.Len(); n != 0 {
+		t.Errorf("expired entry still cached, %d entries", n)
+	}
+
+	// A put renews the TTL
+	cache.put("SoLa", nil)
+	if _, ok := cache.get("SoLa"); !ok {
+		t.Fatal("renewed entry missing")
+	}
+}
+
+func TestStatusCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newStatusCache(time.Minute, 3)
+	for _, wallet := range []string{"SoLa", "SoLb", "SoLc"} {
+		cache.put(wallet, nil)
+	}
+
+	// Using SoLa makes SoLb the least recently used
+	cache.get("SoLa")
+	cache.put("SoLd", nil)
+
+	for wallet, want := range map[string]bool{"SoLa": true, "SoLb": false, "SoLc": true, "SoLd": true} {
+		if _, ok := cache.get(wallet); ok != want {
+			t.Errorf("%s cached = %v, want %v", wallet, ok, want)
+		}
+	}
+	if n := len(cache.items); n != 3 {
+		t.Errorf("cache holds %d entries, want 3", n)
+	}
+}
+
+// TestStatusCacheConcurrent is meant to run with -race.
+func TestStatusCacheConcurrent(t *testing.T) {
+	cache := newStatusCache(time.Millisecond, 16)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				wallet := fmt.Sprintf("SoL%d", (g*7+i)%40)
+				if i%3 == 0 {
+					cache.put(wallet, &models.Client{Address: wallet})
+				} else if client, ok := cache.get(wallet); ok && client != nil && client.Address != wallet {
+					t.Errorf("get(%s) returned the client of %s", wallet, client.Address)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n, m := cache.order.Len(), len(cache.items); n > 16 || n != m {
+		t.Errorf("cache holds %d list entries and %d map entries, want at most 16 of each", n, m)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamFlushRows is how many list entries are written between flushes
+const streamFlushRows = 100
+
+// streamJSONList answers 200 with the object fields plus a list under key
+// whose entries are produced from rows one at a time, flushing as it goes
+// so the list never has to be held in memory. next renders the current row
+// of rows and is passed its 1-based position. A failure after the response
+// has started can only cut it short; it is logged via c.Error.
+func streamJSONList(c *gin.Context, fields gin.H, key string, rows *sql.Rows, next func(n int) (interface{}, error)) {
+	head, err := json.Marshal(fields)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "Internal server error")
+		c.Error(err)
+		return
+	}
+	listKey, _ := json.Marshal(key)
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+	w := c.Writer
+
+	// Open the object without its closing brace, then append the list
+	if len(fields) == 0 {
+		w.WriteString("{")
+	} else {
+		w.Write(head[:len(head)-1])
+		w.WriteString(",")
+	}
+	w.Write(listKey)
+	w.WriteString(":[")
+
+	enc := json.NewEncoder(w)
+	n := 0
+	for rows.Next() {
+		entry, err := next(n + 1)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if n > 0 {
+			w.WriteString(",")
+		}
+		if err := enc.Encode(entry); err != nil {
+			// client went away
+			return
+		}
+		n++
+		if n%streamFlushRows == 0 {
+			w.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		c.Error(err)
+		return
+	}
+	w.WriteString("]}\n")
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"gorm.io/gorm"
+)
+
+// seedEarnings stores count earnings rows spread over wallets wallets
+// within the last hour.
+func seedEarnings(t *testing.T, db *gorm.DB, wallets, count int) {
+	t.Helper()
+	now := time.Now().UTC()
+	earnings := make([]models.ClientEarning, 0, count)
+	for i := 0; i < count; i++ {
+		earnings = append(earnings, models.ClientEarning{
+			ClientAddress: fmt.Sprintf("SoLwallet%05d", i%wallets),
+			Earnings:      int64(1000 + i),
+			Timestamp:     now.Add(-time.Duration(i%3600) * time.Second),
+		})
+	}
+	if err := db.CreateInBatches(&earnings, 500).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeaderboardStreamsLargeDataset(t *testing.T) {
+	cfg := loadTestConfig(t, `{}`)
+	db := openTestDB(t, cfg)
+	seedEarnings(t, db, 2000, 6000)
+	srv := httptest.NewServer(newTestRouter(t, db, cfg))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/network/leaderboard?period=2h&limit=1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("transfer encoding %v, want chunked", resp.TransferEncoding)
+	}
+
+	var body struct {
+		Entries []struct {
+			Rank          int
+			Wallet        string
+			TotalEarnings float64
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding the streamed leaderboard: %v", err)
+	}
+	if len(body.Entries) != maxLeaderboardLimit {
+		t.Fatalf("%d entries, want the %d allowed", len(body.Entries), maxLeaderboardLimit)
+	}
+	for i, entry := range body.Entries {
+		if entry.Rank != i+1 {
+			t.Fatalf("entry %d has rank %d", i, entry.Rank)
+		}
+		if i > 0 && entry.TotalEarnings > body.Entries[i-1].TotalEarnings {
+			t.Fatalf("entry %d (%v) out of order after %v", i, entry.TotalEarnings, body.Entries[i-1].TotalEarnings)
+		}
+	}
+}
+
+func TestExportStreamsLargeDataset(t *testing.T) {
+	cfg := loadTestConfig(t, adminConfig)
+	db := openTestDB(t, cfg)
+	const rows = 3*exportRowsPerFlush + 7
+	seedEarnings(t, db, 100, rows)
+
+	srv := httptest.NewServer(newTestRouter(t, db, cfg))
+	defer srv.Close()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/admin/export", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-API-Key", testAdminKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("transfer encoding %v, want chunked", resp.TransferEncoding)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 + rows; lines != want { // the header, then every earning
+		t.Errorf("exported %d lines, want %d", lines, want)
+	}
+}
+
+// TestStreamReleasesConnectionOnDisconnect hangs up after the first line of
+// an export and expects the database connection back in the pool.
+func TestStreamReleasesConnectionOnDisconnect(t *testing.T) {
+	cfg := loadTestConfig(t, adminConfig)
+	db := openTestDB(t, cfg)
+	seedEarnings(t, db, 100, 20*exportRowsPerFlush)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(newTestRouter(t, db, cfg))
+	defer srv.Close()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/admin/export", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-API-Key", testAdminKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for sqlDB.Stats().InUse > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d database connections still in use after the client went away", sqlDB.Stats().InUse)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// The pool still serves queries
+	var count int64
+	if err := db.Model(&models.ClientEarning{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	sql.Register(testDriverName, timeParsingDriver{&sqlite3.SQLiteDriver{}})
+}
+
+// testDriverName is sqlite with timestamps parsed wherever they appear.
+// The sqlite driver only parses the values of columns declared as
+// timestamps, so aggregates such as MIN(created_at), which Postgres returns
+// as timestamps, would otherwise come back as strings.
+const testDriverName = "sqlite3_times"
+
+type timeParsingDriver struct{ driver.Driver }
+
+func (d timeParsingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return timeParsingConn{conn}, nil
+}
+
+type timeParsingConn struct{ driver.Conn }
+
+func (c timeParsingConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return timeParsingStmt{stmt}, nil
+}
+
+type timeParsingStmt struct{ driver.Stmt }
+
+func (s timeParsingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, err := s.Stmt.Query(args)
+	if err != nil {
+		return nil, err
+	}
+	return timeParsingRows{rows}, nil
+}
+
+type timeParsingRows struct{ driver.Rows }
+
+func (r timeParsingRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return err
+	}
+	for i, value := range dest {
+		if str, ok := value.(string); ok {
+			// the format the sqlite driver stores times in
+			if t, err := time.Parse(sqlite3.SQLiteTimestampFormats[0], str); err == nil {
+				dest[i] = t.UTC()
+			}
+		}
+	}
+	return nil
+}
+
+// testDialector opens the sqlite database at dsn through testDriverName.
+func testDialector(dsn string) gorm.Dialector {
+	return sqlite.Dialector{DriverName: testDriverName, DSN: dsn}
+}
+
+// quietLogger discards the handlers' log output
+var quietLogger = log.New(io.Discard, "", 0)
+
+// loadTestConfig loads configJSON as config.json, with its defaults
+// applied.
+func loadTestConfig(t *testing.T, configJSON string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(configJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	return cfg
+}
+
+// openTestDB opens an empty sqlite database through openDatabaseWith.
+// Writers take the lock when their transaction begins and wait for each
+// other, so concurrent tests behave as on Postgres rather than failing with
+// "database is locked".
+func openTestDB(t *testing.T, cfg *config.Config) *gorm.DB {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "observer.db") + "?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	db, err := openDatabaseWith(testDialector(dsn), cfg)
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if stmtDB, ok := db.ConnPool.(*gorm.PreparedStmtDB); ok {
+			stmtDB.Close()
+		}
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// newTestRouter returns the API router over db, without a block reader.
+func newTestRouter(t *testing.T, db *gorm.DB, cfg *config.Config) *gin.Engine {
+	t.Helper()
+	return setupRouter(routerDeps{
+		DB:     db,
+		Config: cfg,
+		Logger: quietLogger,
+		Warmup: newWarmupState(cfg),
+	})
+}
+
+// serve sends a request with an optional JSON body through router.
+func serve(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeJSON decodes the response body into a generic object, failing the
+// test if the status isn't wantStatus.
+func decodeJSON(t *testing.T, w *httptest.ResponseRecorder, wantStatus int) map[string]interface{} {
+	t.Helper()
+	if w.Code != wantStatus {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, wantStatus, w.Body.String())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+// decodeJSONInto decodes the response body into v.
+func decodeJSONInto(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+	}
+}
+
+// fakeChainNode serves what NewBlockReader and the epoch fetches need: a
+// WebSocket endpoint that acknowledges the subscription, and an epoch API
+// whose current epoch 10 started at epochStart and lasts a day.
+func fakeChainNode(t *testing.T, epochStart time.Time) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/websocket" {
+			conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if _, _, err := conn.ReadMessage(); err == nil {
+				conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+			}
+			return
+		}
+		identifier := strings.TrimPrefix(r.URL.Path, "/epochs/")
+		fmt.Fprintf(w, `{"epoch":{"identifier":%q,"duration":"86400s","current_epoch":"10","current_epoch_start_time":%q}}`,
+			identifier, epochStart.Format(time.RFC3339Nano))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fakeChainConfig is the JSON config of an observer of node, with the extra
+// settings appended.
+func fakeChainConfig(node *httptest.Server, extra string) string {
+	settings := fmt.Sprintf(`"rpc_endpoint": %q, "epoch_endpoint": %q`,
+		"ws"+strings.TrimPrefix(node.URL, "http")+"/websocket", node.URL+"/epochs")
+	if extra != "" {
+		settings += ", " + extra
+	}
+	return "{" + settings + "}"
+}
+
+// newTestRouterWithReader is newTestRouter with a block reader connected to
+// the node the config points to.
+func newTestRouterWithReader(t *testing.T, db *gorm.DB, cfg *config.Config) *gin.Engine {
+	t.Helper()
+	blockReader, err := blockchain.NewBlockReader(cfg, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return setupRouter(routerDeps{
+		DB:          db,
+		BlockReader: blockReader,
+		Config:      cfg,
+		Logger:      quietLogger,
+		Warmup:      newWarmupState(cfg),
+	})
+}
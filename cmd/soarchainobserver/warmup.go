@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"gorm.io/gorm"
+)
+
+// Warmup states reported by /readyz
+const (
+	warmupOff      = "off"
+	warmupRunning  = "running"
+	warmupDone     = "done"
+	warmupFailed   = "failed"
+	warmupTimedOut = "timed out"
+)
+
+// warmupState tracks the startup backfill. It is safe for concurrent use.
+type warmupState struct {
+	mu     sync.Mutex
+	status string
+}
+
+func newWarmupState(cfg *config.Config) *warmupState {
+	if cfg.WarmupEpochs > 0 {
+		return &warmupState{status: warmupRunning}
+	}
+	return &warmupState{status: warmupOff}
+}
+
+func (w *warmupState) get() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *warmupState) set(status string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status = status
+}
+
+// runWarmup backfills the last cfg.WarmupEpochs epochs, giving up after
+// cfg.WarmupTimeout, and records the outcome in state.
+func runWarmup(ctx context.Context, cfg *config.Config, db *gorm.DB, state *warmupState, logger *log.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.WarmupTimeout.Duration)
+	defer cancel()
+
+	logger.Printf("Warmup: backfilling the last %d epochs", cfg.WarmupEpochs)
+	err := blockchain.BackfillRecent(ctx, cfg, db, int64(cfg.WarmupEpochs), logger)
+	switch {
+	case err == nil:
+		logger.Println("Warmup complete")
+		state.set(warmupDone)
+	case errors.Is(err, context.DeadlineExceeded):
+		logger.Printf("Warmup didn't finish within %s, serving what was stored", cfg.WarmupTimeout.Duration)
+		state.set(warmupTimedOut)
+	case errors.Is(err, context.Canceled):
+		state.set(warmupFailed)
+	default:
+		logger.Printf("Warmup failed: %v", err)
+		state.set(warmupFailed)
+	}
+}
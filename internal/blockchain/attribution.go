@@ -0,0 +1,74 @@
+package blockchain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"gorm.io/gorm"
+)
+
+// ReattributeEarnings moves the earnings rows stored under from (a client's
+// core address, used until its solana address is known) to to, within tx.
+// Epoch rows that already exist under to are merged by adding the totals.
+// Epochs are matched by number and start time.
+func ReattributeEarnings(tx *gorm.DB, from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	// Rows keyed by the core address were earned by that client
+	if err := tx.Model(&models.ClientEarning{}).
+		Where("client_address = ?", from).
+		Updates(map[string]interface{}{
+			"client_address": to,
+			"core_address":   gorm.Expr("COALESCE(NULLIF(core_address, ''), ?)", from),
+		}).Error; err != nil {
+		return fmt.Errorf("moving client earnings: %w", err)
+	}
+
+	mergeEpochs := `
+        UPDATE epoch_earnings AS dst
+        SET total_earnings = dst.total_earnings + src.total_earnings,
+            updated_at = ?
+        FROM epoch_earnings src
+        WHERE src.client_address = ? AND dst.client_address = ?
+          AND dst.identifier = src.identifier AND dst.epoch_number = src.epoch_number
+          AND dst.start_time = src.start_time
+    `
+	if err := tx.Exec(mergeEpochs, time.Now().UTC(), from, to).Error; err != nil {
+		return fmt.Errorf("merging epoch earnings: %w", err)
+	}
+	mergeArchive := `
+        UPDATE epoch_archive AS dst
+        SET total_earnings = dst.total_earnings + src.total_earnings
+        FROM epoch_archive src
+        WHERE src.client_address = ? AND dst.client_address = ?
+          AND dst.identifier = src.identifier AND dst.epoch_number = src.epoch_number
+          AND dst.start_time = src.start_time
+    `
+	if err := tx.Exec(mergeArchive, from, to).Error; err != nil {
+		return fmt.Errorf("merging archived epochs: %w", err)
+	}
+
+	// Drop the merged rows, then re-key the remaining ones
+	for _, table := range []string{"epoch_earnings", "epoch_archive"} {
+		dropMerged := fmt.Sprintf(`
+        DELETE FROM %[1]s
+        WHERE client_address = ? AND EXISTS (
+            SELECT 1 FROM %[1]s dst
+            WHERE dst.client_address = ?
+              AND dst.identifier = %[1]s.identifier AND dst.epoch_number = %[1]s.epoch_number
+              AND dst.start_time = %[1]s.start_time
+        )
+    `, table)
+		if err := tx.Exec(dropMerged, from, to).Error; err != nil {
+			return fmt.Errorf("dropping merged %s rows: %w", table, err)
+		}
+		rekey := fmt.Sprintf("UPDATE %s SET client_address = ? WHERE client_address = ?", table)
+		if err := tx.Exec(rekey, to, from).Error; err != nil {
+			return fmt.Errorf("moving %s rows: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+// TestEarningsOfEmptyThenKnownSolanaAddress stores a client first seen
+// without a solana address, then with one.
+func TestEarningsOfEmptyThenKnownSolanaAddress(t *testing.T) {
+	db := openTestDB(t)
+	br, err := newBlockReader(loadTestConfig(t, `{}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)
+	epochs := []EpochInfo{{Identifier: "day", CurrentEpoch: 33, CurrentEpochStart: start, Duration: 24 * time.Hour}}
+	message := func(hash, clientData string) map[string][]string {
+		return map[string][]string{
+			"tx.hash":             {hash},
+			"message.action":      {challengeAction},
+			"message.client_data": {clientData},
+		}
+	}
+
+	br.processEvents(message("TX1", `{"address":"soar1alice","pubkey":"pk","earnings":"100usoar"}`),
+		epochs, start.Add(time.Hour), quietLogger)
+
+	// Without a solana address the earnings are kept under the core address
+	var rows []models.ClientEarning
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].ClientAddress != "soar1alice" {
+		t.Fatalf("earnings rows %+v, want one under soar1alice", rows)
+	}
+
+	br.processEvents(message("TX2", `{"address":"soar1alice","pubkey":"pk","earnings":"50usoar","solanaAddress":"SoLalice"}`),
+		epochs, start.Add(2*time.Hour), quietLogger)
+
+	// Once known, the earlier rows move to the solana address
+	var moved int64
+	if err := db.Model(&models.ClientEarning{}).
+		Where("client_address = ? AND core_address = ?", "SoLalice", "soar1alice").
+		Count(&moved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Errorf("%d earnings rows under SoLalice, want 2", moved)
+	}
+	var epoch []models.EpochEarnings
+	if err := db.Find(&epoch).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(epoch) != 1 || epoch[0].ClientAddress != "SoLalice" || epoch[0].TotalEarnings != 150 {
+		t.Errorf("epoch rows %+v, want one of 150 under SoLalice", epoch)
+	}
+}
+
+func TestReattributeEarningsMergesEpochs(t *testing.T) {
+	db := openTestDB(t)
+	start := time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)
+	live := []models.EpochEarnings{
+		{ClientAddress: "soar1alice", Identifier: "day", EpochNumber: 33, StartTime: start, EndTime: start.Add(24 * time.Hour), TotalEarnings: 100},
+		{ClientAddress: "SoLalice", Identifier: "day", EpochNumber: 33, StartTime: start, EndTime: start.Add(24 * time.Hour), TotalEarnings: 50},
+		{ClientAddress: "soar1alice", Identifier: "day", EpochNumber: 34, StartTime: start.Add(24 * time.Hour), EndTime: start.Add(48 * time.Hour), TotalEarnings: 70},
+	}
+	archived := []models.EpochArchive{
+		{ClientAddress: "soar1alice", Identifier: "day", EpochNumber: 1, StartTime: start.AddDate(0, 0, -32), EndTime: start.AddDate(0, 0, -31), TotalEarnings: 10},
+		{ClientAddress: "SoLalice", Identifier: "day", EpochNumber: 1, StartTime: start.AddDate(0, 0, -32), EndTime: start.AddDate(0, 0, -31), TotalEarnings: 5},
+	}
+	if err := db.Create(&live).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&archived).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReattributeEarnings(db, "soar1alice", "SoLalice"); err != nil {
+		t.Fatal(err)
+	}
+
+	totals := func(model interface{}) map[int64]int64 {
+		var rows []struct {
+			ClientAddress string
+			EpochNumber   int64
+			TotalEarnings int64
+		}
+		if err := db.Model(model).Find(&rows).Error; err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[int64]int64)
+		for _, row := range rows {
+			if row.ClientAddress != "SoLalice" {
+				t.Errorf("epoch %d left under %s", row.EpochNumber, row.ClientAddress)
+			}
+			got[row.EpochNumber] = row.TotalEarnings
+		}
+		return got
+	}
+	if got := totals(&models.EpochEarnings{}); len(got) != 2 || got[33] != 150 || got[34] != 70 {
+		t.Errorf("live epochs %v, want 33: 150, 34: 70", got)
+	}
+	if got := totals(&models.EpochArchive{}); len(got) != 1 || got[1] != 15 {
+		t.Errorf("archived epochs %v, want 1: 15", got)
+	}
+}
@@ -0,0 +1,297 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"gorm.io/gorm"
+)
+
+// backfillPageSize is the number of txs requested per tx_search page
+const backfillPageSize = 100
+
+// Backfill replays the runner_challenge txs of epochs fromEpoch..toEpoch
+// (inclusive, numbered by the primary epoch identifier) through the same path
+// as live messages. Txs already stored are
+// skipped by hash, so running it repeatedly over the same range is safe.
+func Backfill(ctx context.Context, cfg *config.Config, db *gorm.DB, fromEpoch, toEpoch int64, logger *log.Logger) error {
+	if fromEpoch > toEpoch {
+		return fmt.Errorf("from-epoch %d is after to-epoch %d", fromEpoch, toEpoch)
+	}
+
+	br, err := newBlockReader(cfg, db)
+	if err != nil {
+		return err
+	}
+	return br.backfill(ctx, fromEpoch, toEpoch, logger)
+}
+
+// backfill is Backfill with the reader whose RPC client, carrying the TLS
+// and proxy settings of the WebSocket connection, serves every call.
+func (br *BlockReader) backfill(ctx context.Context, fromEpoch, toEpoch int64, logger *log.Logger) error {
+	rpc := &rpcClient{baseURL: rpcHTTPURL(br.URL), http: br.HTTPClient}
+
+	currentEpochs := make([]EpochInfo, 0, len(br.EpochIdentifiers))
+	for _, identifier := range br.EpochIdentifiers {
+		info, err := getCurrentEpoch(br.HTTPClient, br.EpochEndpoint, identifier)
+		if err != nil {
+			return err
+		}
+		currentEpochs = append(currentEpochs, info)
+	}
+	current := currentEpochs[0]
+	if toEpoch > current.CurrentEpoch {
+		return fmt.Errorf("to-epoch %d is after the current epoch %d", toEpoch, current.CurrentEpoch)
+	}
+
+	// Translate the epoch range into a time window, then into block heights
+	start := current.CurrentEpochStart.Add(time.Duration(fromEpoch-current.CurrentEpoch) * current.Duration)
+	end := current.CurrentEpochStart.Add(time.Duration(toEpoch-current.CurrentEpoch+1) * current.Duration)
+
+	fromHeight, toHeight, err := rpc.heightRange(ctx, start, end)
+	if err != nil {
+		return err
+	}
+	if fromHeight > toHeight {
+		logger.Printf("Backfill: no blocks between %s and %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+		return nil
+	}
+	logger.Printf("Backfill: epochs %d-%d map to heights %d-%d", fromEpoch, toEpoch, fromHeight, toHeight)
+
+	query := fmt.Sprintf("message.action='%s' AND tx.height>=%d AND tx.height<=%d", challengeAction, fromHeight, toHeight)
+	blockTimes := make(map[int64]time.Time)
+	processed := 0
+
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		txs, total, err := rpc.txSearch(ctx, query, page)
+		if err != nil {
+			return err
+		}
+
+		for _, tx := range txs {
+			height, err := strconv.ParseInt(tx.Height, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid height %q for tx %s", tx.Height, tx.Hash)
+			}
+			blockTime, ok := blockTimes[height]
+			if !ok {
+				if blockTime, err = rpc.blockTime(ctx, height); err != nil {
+					return err
+				}
+				blockTimes[height] = blockTime
+			}
+
+			// The epochs still select the block range when aggregation is disabled
+			var epochs []EpochInfo
+			if br.EpochAggregation {
+				for _, info := range currentEpochs {
+					epochs = append(epochs, epochAt(info, blockTime))
+				}
+			}
+			br.processEvents(tx.eventMap(), epochs, blockTime, logger)
+			processed++
+		}
+
+		logger.Printf("Backfill: processed %d/%d txs", processed, total)
+		if len(txs) == 0 || processed >= total {
+			break
+		}
+	}
+
+	return nil
+}
+
+// BackfillRecent backfills the last epochs epochs of the primary epoch
+// identifier, up to and including the current one.
+func BackfillRecent(ctx context.Context, cfg *config.Config, db *gorm.DB, epochs int64, logger *log.Logger) error {
+	br, err := newBlockReader(cfg, db)
+	if err != nil {
+		return err
+	}
+	current, err := getCurrentEpoch(br.HTTPClient, br.EpochEndpoint, br.EpochIdentifiers[0])
+	if err != nil {
+		return err
+	}
+	fromEpoch := current.CurrentEpoch - epochs + 1
+	if fromEpoch < 0 {
+		fromEpoch = 0
+	}
+	return br.backfill(ctx, fromEpoch, current.CurrentEpoch, logger)
+}
+
+// rpcHTTPURL derives the Tendermint RPC HTTP base URL from the WebSocket
+// endpoint, e.g. wss://host/websocket -> https://host.
+func rpcHTTPURL(wsURL string) string {
+	u := strings.TrimSuffix(wsURL, "/websocket")
+	u = strings.Replace(u, "wss://", "https://", 1)
+	u = strings.Replace(u, "ws://", "http://", 1)
+	return u
+}
+
+// rpcClient is a minimal client for the Tendermint RPC HTTP endpoints that
+// backfill needs.
+type rpcClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// searchedTx is one entry of a tx_search result.
+type searchedTx struct {
+	Hash     string `json:"hash"`
+	Height   string `json:"height"`
+	TxResult struct {
+		Events []struct {
+			Type       string `json:"type"`
+			Attributes []struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+			} `json:"attributes"`
+		} `json:"events"`
+	} `json:"tx_result"`
+}
+
+// eventMap flattens the tx events into the "type.key" -> values shape used
+// by WebSocket event messages.
+func (tx searchedTx) eventMap() map[string][]string {
+	events := map[string][]string{
+		"tx.hash":   {tx.Hash},
+		"tx.height": {tx.Height},
+	}
+	for _, event := range tx.TxResult.Events {
+		for _, attr := range event.Attributes {
+			key := event.Type + "." + attr.Key
+			events[key] = append(events[key], attr.Value)
+		}
+	}
+	return events
+}
+
+func (c *rpcClient) get(ctx context.Context, path string, params url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code from %s: %d", path, resp.StatusCode)
+	}
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+			Data    string `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", path, err)
+	}
+	if envelope.Error != nil {
+		return fmt.Errorf("%s failed: %s %s", path, envelope.Error.Message, envelope.Error.Data)
+	}
+	return json.Unmarshal(envelope.Result, out)
+}
+
+func (c *rpcClient) txSearch(ctx context.Context, query string, page int) ([]searchedTx, int, error) {
+	params := url.Values{}
+	params.Set("query", strconv.Quote(query))
+	params.Set("page", strconv.Itoa(page))
+	params.Set("per_page", strconv.Itoa(backfillPageSize))
+	params.Set("order_by", `"asc"`)
+
+	var result struct {
+		Txs        []searchedTx `json:"txs"`
+		TotalCount string       `json:"total_count"`
+	}
+	if err := c.get(ctx, "/tx_search", params, &result); err != nil {
+		return nil, 0, err
+	}
+	total, err := strconv.Atoi(result.TotalCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid total_count %q", result.TotalCount)
+	}
+	return result.Txs, total, nil
+}
+
+func (c *rpcClient) blockTime(ctx context.Context, height int64) (time.Time, error) {
+	params := url.Values{}
+	params.Set("height", strconv.FormatInt(height, 10))
+
+	var result struct {
+		Header struct {
+			Time time.Time `json:"time"`
+		} `json:"header"`
+	}
+	if err := c.get(ctx, "/header", params, &result); err != nil {
+		return time.Time{}, err
+	}
+	return result.Header.Time.UTC(), nil
+}
+
+// heightRange returns the first and last block heights whose block time lies
+// within [start, end), using a binary search over the node's available blocks.
+func (c *rpcClient) heightRange(ctx context.Context, start, end time.Time) (int64, int64, error) {
+	var status struct {
+		SyncInfo struct {
+			EarliestBlockHeight string `json:"earliest_block_height"`
+			LatestBlockHeight   string `json:"latest_block_height"`
+		} `json:"sync_info"`
+	}
+	if err := c.get(ctx, "/status", url.Values{}, &status); err != nil {
+		return 0, 0, err
+	}
+	earliest, err := strconv.ParseInt(status.SyncInfo.EarliestBlockHeight, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid earliest_block_height: %w", err)
+	}
+	latest, err := strconv.ParseInt(status.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid latest_block_height: %w", err)
+	}
+
+	from, err := c.firstHeightAtOrAfter(ctx, earliest, latest, start)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := c.firstHeightAtOrAfter(ctx, earliest, latest, end)
+	if err != nil {
+		return 0, 0, err
+	}
+	return from, to - 1, nil
+}
+
+// firstHeightAtOrAfter returns the lowest height in [low, high] whose block
+// time is not before t, or high+1 if every block is older.
+func (c *rpcClient) firstHeightAtOrAfter(ctx context.Context, low, high int64, t time.Time) (int64, error) {
+	result := high + 1
+	for low <= high {
+		mid := low + (high-low)/2
+		blockTime, err := c.blockTime(ctx, mid)
+		if err != nil {
+			return 0, err
+		}
+		if blockTime.Before(t) {
+			low = mid + 1
+		} else {
+			result = mid
+			high = mid - 1
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+// fakeChain is a node whose day epochs last an hour: epoch 5 is current and
+// began at epochStart. Block h is produced 10 minutes after block h-1, block
+// 1 at the start of epoch 4, and txs holds the runner_challenge txs by
+// height. It serves the RPC endpoints backfill uses and the epoch API.
+type fakeChain struct {
+	epochStart time.Time
+	txs        map[int64][]string // client_data entries by height
+}
+
+const fakeChainLatestHeight = 12
+
+func (f *fakeChain) blockTime(height int64) time.Time {
+	return f.epochStart.Add(-time.Hour).Add(time.Duration(height-1) * 10 * time.Minute)
+}
+
+func (f *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result := func(v interface{}) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": -1, "result": v})
+	}
+	switch r.URL.Path {
+	case "/epochs/day":
+		fmt.Fprintf(w, `{"epoch":{"identifier":"day","duration":"3600s","current_epoch":"5","current_epoch_start_time":%q}}`,
+			f.epochStart.Format(time.RFC3339Nano))
+	case "/status":
+		result(map[string]interface{}{
+			"node_info": map[string]string{"network": "soarchain-test-1"},
+			"sync_info": map[string]string{"earliest_block_height": "1", "latest_block_height": strconv.Itoa(fakeChainLatestHeight)},
+		})
+	case "/header":
+		height, _ := strconv.ParseInt(r.URL.Query().Get("height"), 10, 64)
+		result(map[string]interface{}{"header": map[string]string{"time": f.blockTime(height).Format(time.RFC3339Nano)}})
+	case "/tx_search":
+		var txs []map[string]interface{}
+		if r.URL.Query().Get("page") == "1" {
+			for height := int64(1); height <= fakeChainLatestHeight; height++ {
+				for i, clientData := range f.txs[height] {
+					txs = append(txs, map[string]interface{}{
+						"hash":   fmt.Sprintf("TX%d-%d", height, i),
+						"height": strconv.FormatInt(height, 10),
+						"tx_result": map[string]interface{}{"events": []map[string]interface{}{{
+							"type": "message",
+							"attributes": []map[string]string{
+								{"key": "action", "value": challengeAction},
+								{"key": "client_data", "value": clientData},
+							},
+						}}},
+					})
+				}
+			}
+		}
+		total := 0
+		for _, list := range f.txs {
+			total += len(list)
+		}
+		result(map[string]interface{}{"txs": txs, "total_count": strconv.Itoa(total)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRPCClientOverTLS(t *testing.T) {
+	chain := &fakeChain{epochStart: time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC)}
+	srv := httptest.NewTLSServer(chain)
+	defer srv.Close()
+
+	cfg := loadTestConfig(t, fmt.Sprintf(`{"rpc_endpoint": %q, "tls_ca_cert_path": %q}`, wsURL(srv), caCertFile(t, srv)))
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpc := &rpcClient{baseURL: rpcHTTPURL(cfg.RPCEndpoint), http: client}
+
+	// Epoch 5 spans heights 7-12
+	from, to, err := rpc.heightRange(context.Background(), chain.epochStart, chain.epochStart.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("heightRange: %v", err)
+	}
+	if from != 7 || to != 12 {
+		t.Fatalf("heightRange = %d-%d, want 7-12", from, to)
+	}
+	if _, _, err := rpc.txSearch(context.Background(), "tx.height>=7", 1); err != nil {
+		t.Fatalf("txSearch: %v", err)
+	}
+}
+
+func TestBackfillIsIdempotent(t *testing.T) {
+	const address = "soar1backfillclient"
+	chain := &fakeChain{
+		epochStart: time.Date(2025, 1, 1, 4, 0, 0, 0, time.UTC),
+		txs: map[int64][]string{
+			2: {`{"address":"` + address + `","pubkey":"pk","earnings":"1500usoar","solanaAddress":"SoLwallet"}`},
+			8: {`{"address":"` + address + `","pubkey":"pk","earnings":"2500usoar","solanaAddress":"SoLwallet"}`},
+		},
+	}
+	srv := httptest.NewTLSServer(chain)
+	defer srv.Close()
+
+	cfg := loadTestConfig(t, fmt.Sprintf(`{"rpc_endpoint": %q, "epoch_endpoint": %q, "tls_ca_cert_path": %q}`,
+		wsURL(srv), srv.URL+"/epochs", caCertFile(t, srv)))
+	db := openTestDB(t)
+
+	for run := 1; run <= 2; run++ {
+		if err := Backfill(context.Background(), cfg, db, 4, 5, quietLogger); err != nil {
+			t.Fatalf("Backfill run %d: %v", run, err)
+		}
+	}
+
+	var earnings []models.ClientEarning
+	if err := db.Order("timestamp").Find(&earnings).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(earnings) != 2 {
+		t.Fatalf("stored %d earnings rows over two runs, want 2", len(earnings))
+	}
+	for i, height := range []int64{2, 8} {
+		if want := chain.blockTime(height); !earnings[i].Timestamp.Equal(want) {
+			t.Errorf("earning %d timestamp = %s, want block time %s", i, earnings[i].Timestamp, want)
+		}
+	}
+
+	var client models.Client
+	if err := db.First(&client, "address = ?", address).Error; err != nil {
+		t.Fatal(err)
+	}
+	if client.TotalLifetimeEarnings != 4000 {
+		t.Errorf("lifetime earnings = %d, want 4000", client.TotalLifetimeEarnings)
+	}
+
+	var epochs []models.EpochEarnings
+	if err := db.Order("epoch_number").Find(&epochs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(epochs) != 2 || epochs[0].EpochNumber != 4 || epochs[0].TotalEarnings != 1500 ||
+		epochs[1].EpochNumber != 5 || epochs[1].TotalEarnings != 2500 {
+		t.Fatalf("epoch rows = %+v, want epoch 4 with 1500 and epoch 5 with 2500", epochs)
+	}
+}
@@ -0,0 +1,31 @@
+package blockchain
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// eventTime returns the time of the block the events' tx was included in,
+// looked up by the tx.height attribute. It falls back to now when the
+// height is missing or the block header can't be fetched, so a flaky RPC
+// doesn't stop ingestion. Only the reader goroutine calls it.
+func (br *BlockReader) eventTime(events map[string][]string, logger *log.Logger) time.Time {
+	height := parseHeight(firstEvent(events, "tx.height"))
+	if height <= 0 {
+		return time.Now().UTC()
+	}
+	// The txs of one block arrive one after another
+	if height == br.lastBlockHeight {
+		return br.lastBlockTime
+	}
+
+	rpc := &rpcClient{baseURL: rpcHTTPURL(br.URL), http: br.HTTPClient}
+	blockTime, err := rpc.blockTime(context.Background(), height)
+	if err != nil {
+		logger.Printf("Error fetching time of block %d, using the current time: %v", height, err)
+		return time.Now().UTC()
+	}
+	br.lastBlockHeight, br.lastBlockTime = height, blockTime
+	return blockTime
+}
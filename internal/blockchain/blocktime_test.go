@@ -0,0 +1,133 @@
+package blockchain
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+)
+
+var quietLogger = log.New(io.Discard, "", 0)
+
+// headerHandler serves the RPC /header endpoint with blockTime for every
+// height, counting the calls.
+func headerHandler(blockTime time.Time, calls *atomic.Int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path != "/header" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":-1,"result":{"header":{"height":%q,"time":%q}}}`,
+			r.URL.Query().Get("height"), blockTime.Format(time.RFC3339Nano))
+	}
+}
+
+// assertAboutNow fails unless got lies between before and the current time.
+func assertAboutNow(t *testing.T, got, before time.Time) {
+	t.Helper()
+	if got.Before(before) || got.After(time.Now().UTC()) {
+		t.Fatalf("time = %s, want the current time (after %s)", got, before)
+	}
+}
+
+func TestEventTimeUsesBlockTime(t *testing.T) {
+	blockTime := time.Date(2025, 1, 16, 9, 4, 54, 123000000, time.UTC)
+	var calls atomic.Int64
+	srv := httptest.NewServer(headerHandler(blockTime, &calls))
+	defer srv.Close()
+
+	br := &BlockReader{URL: wsURL(srv), HTTPClient: srv.Client()}
+	events := map[string][]string{"tx.height": {"42"}}
+
+	if got := br.eventTime(events, quietLogger); !got.Equal(blockTime) {
+		t.Fatalf("eventTime = %s, want block time %s", got, blockTime)
+	}
+	// Further txs of the same block reuse the looked up time
+	if got := br.eventTime(events, quietLogger); !got.Equal(blockTime) {
+		t.Fatalf("eventTime (cached) = %s, want %s", got, blockTime)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("header requests = %d, want 1", n)
+	}
+}
+
+func TestEventTimeFallsBackToNow(t *testing.T) {
+	var calls atomic.Int64
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	tests := []struct {
+		name   string
+		events map[string][]string
+	}{
+		{"missing height", map[string][]string{}},
+		{"invalid height", map[string][]string{"tx.height": {"abc"}}},
+		{"header request fails", map[string][]string{"tx.height": {"7"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			br := &BlockReader{URL: wsURL(failing), HTTPClient: failing.Client()}
+			before := time.Now().UTC()
+			assertAboutNow(t, br.eventTime(tt.events, quietLogger), before)
+		})
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("header requests = %d, want 1 (only for a valid height)", n)
+	}
+}
+
+func TestEventTimeSlowServer(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	cfg := &config.Config{}
+	cfg.HTTPTimeout.Duration = 100 * time.Millisecond
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := &BlockReader{URL: wsURL(slow), HTTPClient: client}
+
+	before := time.Now().UTC()
+	got := br.eventTime(map[string][]string{"tx.height": {"9"}}, quietLogger)
+	if elapsed := time.Since(before); elapsed > 2*time.Second {
+		t.Fatalf("eventTime took %s, want it bounded by the HTTP timeout", elapsed)
+	}
+	assertAboutNow(t, got, before)
+}
+
+func TestEventTimeOverTLSWithCustomCA(t *testing.T) {
+	blockTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	var calls atomic.Int64
+	srv := httptest.NewTLSServer(headerHandler(blockTime, &calls))
+	defer srv.Close()
+
+	cfg := &config.Config{TLSCACertPath: caCertFile(t, srv)}
+	cfg.HTTPTimeout.Duration = 5 * time.Second
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := &BlockReader{URL: wsURL(srv), HTTPClient: client}
+
+	if got := br.eventTime(map[string][]string{"tx.height": {"3"}}, quietLogger); !got.Equal(blockTime) {
+		t.Fatalf("eventTime = %s, want block time %s from the self-signed node", got, blockTime)
+	}
+}
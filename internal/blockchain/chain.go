@@ -0,0 +1,61 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+)
+
+// VerifyChainID checks that the RPC node serves the chain configured as
+// cfg.ChainID. On a mismatch it returns an error, or only logs it when
+// cfg.AllowChainIDMismatch is set. Without a configured chain ID it just
+// logs the node's.
+func VerifyChainID(ctx context.Context, cfg *config.Config, logger *log.Logger) error {
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return err
+	}
+	rpc := &rpcClient{baseURL: rpcHTTPURL(cfg.RPCEndpoint), http: httpClient}
+	chainID, err := rpc.chainID(ctx)
+	if err != nil {
+		if cfg.ChainID == "" {
+			logger.Printf("Error fetching chain ID: %v", err)
+			return nil
+		}
+		return fmt.Errorf("fetching chain ID: %w", err)
+	}
+
+	switch {
+	case cfg.ChainID == "":
+		logger.Printf("RPC node serves chain %s (network %s); set chain_id to enforce it", chainID, cfg.Network)
+		return nil
+	case chainID == cfg.ChainID:
+		logger.Printf("RPC node serves chain %s", chainID)
+		return nil
+	}
+	mismatch := fmt.Errorf("RPC node serves chain %q, but chain_id is %q (network %s)", chainID, cfg.ChainID, cfg.Network)
+	if cfg.AllowChainIDMismatch {
+		logger.Printf("WARNING: %v; recording its data anyway", mismatch)
+		return nil
+	}
+	return mismatch
+}
+
+// chainID returns the chain ID reported by the node's /status endpoint.
+func (c *rpcClient) chainID(ctx context.Context) (string, error) {
+	var status struct {
+		NodeInfo struct {
+			Network string `json:"network"`
+		} `json:"node_info"`
+	}
+	if err := c.get(ctx, "/status", url.Values{}, &status); err != nil {
+		return "", err
+	}
+	if status.NodeInfo.Network == "" {
+		return "", fmt.Errorf("status response has no node_info.network")
+	}
+	return status.NodeInfo.Network, nil
+}
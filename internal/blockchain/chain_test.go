@@ -0,0 +1,67 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+)
+
+// statusServer serves the RPC /status endpoint of a node on chainID behind
+// a self-signed certificate.
+func statusServer(t *testing.T, chainID string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":-1,"result":{"node_info":{"network":%q}}}`, chainID)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVerifyChainID(t *testing.T) {
+	srv := statusServer(t, "soarchain-mainnet-1")
+	down := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	tests := []struct {
+		name          string
+		node          *httptest.Server
+		chainID       string
+		allowMismatch bool
+		wantErr       bool
+	}{
+		{"match", srv, "soarchain-mainnet-1", false, false},
+		{"mismatch", srv, "soarchain-testnet-1", false, true},
+		{"mismatch allowed", srv, "soarchain-testnet-1", true, false},
+		{"no chain_id configured", srv, "", false, false},
+		{"node unreachable with chain_id", down, "soarchain-mainnet-1", false, true},
+		{"node unreachable without chain_id", down, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Network:              "mainnet",
+				RPCEndpoint:          wsURL(tt.node),
+				TLSCACertPath:        caCertFile(t, tt.node),
+				ChainID:              tt.chainID,
+				AllowChainIDMismatch: tt.allowMismatch,
+			}
+			cfg.HTTPTimeout.Duration = 5 * time.Second
+
+			err := VerifyChainID(context.Background(), cfg, quietLogger)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyChainID() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package blockchain
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/gorilla/websocket"
+)
+
+// newDialer builds the WebSocket dialer used by Connect, applying the TLS
+// and proxy settings from the config. Certificate verification stays on
+// unless it is explicitly disabled.
+func newDialer(cfg *config.Config) (*websocket.Dialer, error) {
+	dialer := *websocket.DefaultDialer
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dialer.TLSClientConfig = tlsConfig
+
+	proxy, err := newProxyFunc(cfg)
+	if err != nil {
+		return nil, err
+	}
+	dialer.Proxy = proxy
+
+	return &dialer, nil
+}
+
+// newHTTPClient builds the client used for REST calls such as the epoch
+// API and the node's RPC endpoints, sharing the TLS and proxy settings of
+// the WebSocket dialer. The client is shared by all calls so connections are
+// reused, and every call is bounded by the configured timeout.
+func newHTTPClient(cfg *config.Config) (*http.Client, error) {
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := newProxyFunc(cfg)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.Proxy = proxy
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.HTTPTimeout.Duration,
+	}, nil
+}
+
+// newProxyFunc returns the proxy selector for outgoing connections. An
+// explicit ProxyURL wins; otherwise the standard proxy env vars apply.
+func newProxyFunc(cfg *config.Config) (func(*http.Request) (*url.URL, error), error) {
+	if cfg.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	proxyURL, err := url.Parse(cfg.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy_url: %w", err)
+	}
+	return http.ProxyURL(proxyURL), nil
+}
+
+// newTLSConfig returns the TLS client config for the node connection,
+// or nil to use the system defaults.
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	if !cfg.TLSInsecureSkipVerify && cfg.TLSCACertPath == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+
+	if cfg.TLSCACertPath != "" {
+		pem, err := os.ReadFile(cfg.TLSCACertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA cert: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", cfg.TLSCACertPath)
+		}
+		tlsConfig.RootCAs = pool
+		log.Printf("Using additional CA certificates from %s", cfg.TLSCACertPath)
+	}
+
+	if cfg.TLSInsecureSkipVerify {
+		log.Println("WARNING: TLS certificate verification is DISABLED for the RPC connection (tls_insecure_skip_verify=true)")
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return tlsConfig, nil
+}
+
+// redactHeader renders header names for logging without their values,
+// which usually carry credentials.
+func redactHeader(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name+"=<redacted>")
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
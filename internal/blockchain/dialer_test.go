@@ -0,0 +1,146 @@
+package blockchain
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+)
+
+// caCertFile writes the certificate of a TLS test server to a PEM file, as
+// the tls_ca_cert_path setting of a self-signed node would point to.
+func caCertFile(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// wsURL returns the WebSocket endpoint an observer would be configured with
+// for the RPC node served by srv.
+func wsURL(srv *httptest.Server) string {
+	if srv.TLS != nil {
+		return "wss" + srv.URL[len("https"):] + "/websocket"
+	}
+	return "ws" + srv.URL[len("http"):] + "/websocket"
+}
+
+func TestNewHTTPClientTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{"system roots reject the node", config.Config{}, true},
+		{"custom CA", config.Config{TLSCACertPath: caCertFile(t, srv)}, false},
+		{"insecure skip verify", config.Config{TLSInsecureSkipVerify: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.HTTPTimeout.Duration = 5 * time.Second
+			client, err := newHTTPClient(&tt.cfg)
+			if err != nil {
+				t.Fatalf("newHTTPClient: %v", err)
+			}
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GET error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfiguredProxyIsUsed(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Method+" "+r.Host)
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+
+	cfg := &config.Config{ProxyURL: proxy.URL}
+	cfg.HTTPTimeout.Duration = 5 * time.Second
+
+	dialer, err := newDialer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := dialer.Dial("ws://rpc.example:26657/websocket", nil); err == nil {
+		t.Error("dial through the refusing proxy succeeded")
+	}
+
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get("http://api.example/soarchain/epochs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"CONNECT rpc.example:26657", "GET api.example"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("proxy saw %v, want %v", seen, want)
+	}
+}
+
+func TestInvalidProxyURL(t *testing.T) {
+	if _, err := newDialer(&config.Config{ProxyURL: "http://[::1"}); err == nil {
+		t.Error("newDialer accepted an invalid proxy URL")
+	}
+}
+
+func TestEpochFetchTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	if br, err := newBlockReader(loadTestConfig(t, `{}`), nil); err != nil {
+		t.Fatal(err)
+	} else if br.HTTPClient.Timeout != 5*time.Second {
+		t.Errorf("default timeout %s, want 5s", br.HTTPClient.Timeout)
+	}
+
+	br, err := newBlockReader(loadTestConfig(t, `{"http_timeout": "100ms"}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = getCurrentEpoch(br.HTTPClient, slow.URL+"/epochs", "day")
+	if err == nil {
+		t.Fatal("getCurrentEpoch succeeded against a hung server")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("getCurrentEpoch returned after %s, want about 100ms", elapsed)
+	}
+}
@@ -0,0 +1,32 @@
+package blockchain
+
+import (
+	"log"
+	"time"
+)
+
+// logDryRun logs, one line per client, the earnings processEvents would
+// store for a tx in dry-run mode.
+func (br *BlockReader) logDryRun(txHash string, records []clientRecord, epochs []EpochInfo, timestamp time.Time, logger *log.Logger) {
+	epochNumbers := make(map[string]int64, len(epochs))
+	for _, epoch := range epochs {
+		epochNumbers[epoch.Identifier] = epoch.CurrentEpoch
+	}
+	for _, record := range records {
+		data := record.Data
+		earningsValue, err := br.parseEarnings(data.Earnings)
+		if err != nil {
+			logger.Printf("Skipping earnings of client %s: %v", data.Address, err)
+			continue
+		}
+		br.Log.Info("dry run: would store earnings",
+			"tx", txHash,
+			"client", data.Address,
+			"pubkey", data.PubKey,
+			"solanaAddress", record.SolanaAddress,
+			"solanaSource", record.SolanaSource,
+			"earnings", earningsValue,
+			"timestamp", timestamp,
+			"epochs", epochNumbers)
+	}
+}
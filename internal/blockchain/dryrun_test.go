@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/metrics"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestDryRunStoresNothing processes a challenge tx in dry-run mode and
+// expects the would-be writes logged, the metrics counted and the database
+// left untouched.
+func TestDryRunStoresNothing(t *testing.T) {
+	var posts atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+	}))
+	defer webhook.Close()
+
+	db := openTestDB(t)
+	br, err := newBlockReader(loadTestConfig(t, `{"dry_run": true, "epoch_webhook_url": "`+webhook.URL+`"}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := captureLog(br)
+	invalidClientData := metrics.MalformedMessages.WithLabelValues("invalid_client_data")
+	before := testutil.ToFloat64(invalidClientData)
+
+	now := time.Now().UTC()
+	epoch := EpochInfo{Identifier: "day", CurrentEpoch: 7, CurrentEpochStart: now.Add(-time.Hour), Duration: 24 * time.Hour}
+	br.processEvents(map[string][]string{
+		"tx.hash":        {"TXDRY"},
+		"message.action": {challengeAction},
+		"message.client_data": {
+			`{"address":"soar1alice","pubkey":"pk","earnings":"100usoar"}`,
+			`not json`,
+		},
+	}, []EpochInfo{epoch}, now, quietLogger)
+
+	logged := logs.String()
+	for _, field := range []string{`"msg":"dry run: would store earnings"`, `"tx":"TXDRY"`, `"client":"soar1alice"`, `"earnings":100`} {
+		if !strings.Contains(logged, field) {
+			t.Errorf("log %q is missing %s", logged, field)
+		}
+	}
+	if got := testutil.ToFloat64(invalidClientData) - before; got != 1 {
+		t.Errorf("invalid client data counter went up by %v, want 1", got)
+	}
+	for _, model := range []interface{}{&models.Client{}, &models.ClientEarning{}, &models.EpochEarnings{}, &models.ProcessedTx{}} {
+		var count int64
+		if err := db.Model(model).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("dry run stored %d %T rows", count, model)
+		}
+	}
+
+	// Finalized epochs are only logged
+	br.announceEpochFinalized(EpochFinalizedEvent{Type: "epoch_finalized", Identifier: "day", EpochNumber: 6})
+	if n := posts.Load(); n != 0 {
+		t.Errorf("dry run posted %d epoch webhooks", n)
+	}
+}
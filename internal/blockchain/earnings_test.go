@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEarnings(t *testing.T) {
+	tests := []struct {
+		name        string
+		earnings    coins
+		want        int64
+		wantErr     string
+		wantSkipped string // coins the debug log must mention
+	}{
+		{name: "single coin", earnings: coins{"1500usoar"}, want: 1500},
+		{name: "bare amount", earnings: coins{"1500"}, want: 1500},
+		{name: "comma separated", earnings: coins{"1500usoar,500usoar"}, want: 2000},
+		{name: "list of coins", earnings: coins{"1500usoar", "250usoar"}, want: 1750},
+		{name: "spaces around coins", earnings: coins{" 1500usoar , 500usoar "}, want: 2000},
+		{name: "mixed denoms", earnings: coins{"1500usoar,42uatom"}, want: 1500, wantSkipped: "42uatom"},
+		{name: "mixed denoms in a list", earnings: coins{"7ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", "1500usoar"},
+			want: 1500, wantSkipped: "7ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2"},
+		{name: "no coin in the denom", earnings: coins{"42uatom"}, wantErr: `no earnings in denom "usoar"`},
+		{name: "empty", earnings: coins{}, wantErr: `no earnings in denom "usoar"`},
+		{name: "negative", earnings: coins{"-5usoar"}, wantErr: "invalid earnings"},
+		{name: "malformed", earnings: coins{"usoar1500"}, wantErr: "invalid earnings"},
+		{name: "amount out of range", earnings: coins{"9223372036854775808usoar"}, wantErr: "invalid earnings amount"},
+		{name: "total overflows", earnings: coins{"9223372036854775807usoar", "1usoar"}, wantErr: "overflow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			br := &BlockReader{ExpectedDenom: "usoar"}
+			logged := captureLog(br)
+
+			got, err := br.parseEarnings(tt.earnings)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseEarnings(%v) = %d, %v; want an error mentioning %q", tt.earnings, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("parseEarnings(%v) = %d, %v; want %d", tt.earnings, got, err, tt.want)
+			}
+
+			if tt.wantSkipped == "" {
+				if logged.Len() > 0 {
+					t.Errorf("unexpected log %q", logged)
+				}
+			} else if !strings.Contains(logged.String(), "ignoring earnings in other denoms") ||
+				!strings.Contains(logged.String(), tt.wantSkipped) {
+				t.Errorf("log %q doesn't report skipping %s", logged, tt.wantSkipped)
+			}
+		})
+	}
+}
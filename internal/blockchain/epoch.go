@@ -0,0 +1,196 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+)
+
+// maxErrorBodyBytes bounds how much of a failed response is kept in errors
+const maxErrorBodyBytes = 512
+
+// defaultEpochBackoff is how long epoch fetches pause after a 429 or 5xx
+// response that doesn't say when to retry.
+const defaultEpochBackoff = 30 * time.Second
+
+// EpochAPIError is returned for non-200 responses from the epoch API. It
+// keeps the start of the response body, which usually explains the failure.
+type EpochAPIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func newEpochAPIError(resp *http.Response) *EpochAPIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	apiErr := &EpochAPIError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+func (e *EpochAPIError) Error() string {
+	kind := "unexpected status code"
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		kind = "rate limited by epoch API"
+	case e.StatusCode >= 500:
+		kind = "epoch API server error"
+	}
+	return fmt.Sprintf("%s: %d: %s", kind, e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the failure is worth retrying later (429/5xx),
+// as opposed to a request the API will keep rejecting.
+func (e *EpochAPIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// EpochStatus describes the epoch info last fetched from the chain and how
+// fresh it is.
+type EpochStatus struct {
+	Info          EpochInfo
+	LastFetchedAt time.Time
+	LastError     string
+	LastErrorAt   time.Time
+	BackoffUntil  time.Time // fetches are paused until then after a 429/5xx
+}
+
+// currentEpoch fetches the current epoch with the given identifier and
+// caches it. When the epoch API fails, the last cached epoch is projected to
+// now instead, so a short API outage doesn't stop ingestion; the failure
+// stays visible via EpochStatus.
+func (br *BlockReader) currentEpoch(identifier string, logger *log.Logger) (EpochInfo, error) {
+	// While backing off from a 429/5xx, don't hit the API at all
+	br.epochMu.Lock()
+	cached := br.epochStatusLocked(identifier)
+	if now := time.Now().UTC(); now.Before(cached.BackoffUntil) && !cached.LastFetchedAt.IsZero() {
+		info := epochAt(cached.Info, now)
+		br.epochMu.Unlock()
+		return info, nil
+	}
+	br.epochMu.Unlock()
+
+	info, err := getCurrentEpoch(br.HTTPClient, br.EpochEndpoint, identifier)
+
+	br.epochMu.Lock()
+	cached = br.epochStatusLocked(identifier)
+
+	now := time.Now().UTC()
+	if err == nil {
+		if !cached.LastFetchedAt.IsZero() && info.CurrentEpoch < cached.Info.CurrentEpoch {
+			logger.Printf("Warning: %s epoch number went back from %d to %d; the chain may have been reset",
+				identifier, cached.Info.CurrentEpoch, info.CurrentEpoch)
+		}
+		// The epochs between the last fetched one and this one have ended
+		ended := !cached.LastFetchedAt.IsZero() && info.CurrentEpoch > cached.Info.CurrentEpoch
+		last := cached.Info
+		cached.Info = info
+		cached.LastFetchedAt = now
+		cached.BackoffUntil = time.Time{}
+		br.epochMu.Unlock()
+
+		// Finalizing uses the DB and webhooks, so it runs outside the lock
+		// but on the caller's goroutine, which shutdown waits for before
+		// closing the DB.
+		if ended {
+			br.finalizeEpochs(last, info.CurrentEpoch, logger)
+		}
+		return info, nil
+	}
+	defer br.epochMu.Unlock()
+
+	cached.LastError = err.Error()
+	cached.LastErrorAt = now
+
+	var apiErr *EpochAPIError
+	if errors.As(err, &apiErr) && apiErr.Temporary() {
+		backoff := apiErr.RetryAfter
+		if backoff == 0 {
+			backoff = defaultEpochBackoff
+		}
+		cached.BackoffUntil = now.Add(backoff)
+		logger.Printf("Epoch API unavailable, pausing %s epoch fetches for %s", identifier, backoff)
+	}
+
+	if cached.LastFetchedAt.IsZero() {
+		return EpochInfo{}, err
+	}
+	logger.Printf("Error fetching %s epoch info, falling back to cached epoch from %s: %v",
+		identifier, cached.LastFetchedAt.Format(time.RFC3339), err)
+	return epochAt(cached.Info, now), nil
+}
+
+// epochURL appends the epoch identifier to the epoch API base URL.
+func epochURL(endpoint, identifier string) (string, error) {
+	if !config.ValidEpochIdentifier(identifier) {
+		return "", fmt.Errorf("invalid epoch identifier %q", identifier)
+	}
+	return url.JoinPath(endpoint, identifier)
+}
+
+// epochStatusLocked returns the cache entry for identifier, creating it if
+// needed. br.epochMu must be held.
+func (br *BlockReader) epochStatusLocked(identifier string) *EpochStatus {
+	status, ok := br.epochs[identifier]
+	if !ok {
+		status = &EpochStatus{}
+		br.epochs[identifier] = status
+	}
+	return status
+}
+
+// EpochStatus returns a snapshot of the epoch cache, keyed by identifier.
+func (br *BlockReader) EpochStatus() map[string]EpochStatus {
+	br.epochMu.Lock()
+	defer br.epochMu.Unlock()
+	snapshot := make(map[string]EpochStatus, len(br.EpochIdentifiers))
+	for _, identifier := range br.EpochIdentifiers {
+		snapshot[identifier] = *br.epochStatusLocked(identifier)
+	}
+	return snapshot
+}
+
+// CachedEpoch returns the epoch with the given identifier containing now,
+// derived from the last fetched epoch without calling the epoch API. It
+// reports false when the epoch has never been fetched.
+func (br *BlockReader) CachedEpoch(identifier string) (EpochInfo, bool) {
+	br.epochMu.Lock()
+	defer br.epochMu.Unlock()
+	cached, ok := br.epochs[identifier]
+	if !ok || cached.LastFetchedAt.IsZero() {
+		return EpochInfo{}, false
+	}
+	return epochAt(cached.Info, time.Now().UTC()), true
+}
+
+// EpochStale reports whether any epoch is failing to refresh: its most recent
+// fetch failed and its last success is older than EpochStaleAfter. A reader
+// that simply hasn't needed to fetch yet is not stale.
+func (br *BlockReader) EpochStale() bool {
+	for _, status := range br.EpochStatus() {
+		if status.stale(br.EpochStaleAfter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s EpochStatus) stale(after time.Duration) bool {
+	if s.LastErrorAt.IsZero() || s.LastErrorAt.Before(s.LastFetchedAt) {
+		return false
+	}
+	return time.Since(s.LastFetchedAt) > after
+}
@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestEpochURL(t *testing.T) {
+	tests := []struct {
+		endpoint, identifier string
+		want                 string
+		wantErr              bool
+	}{
+		{endpoint: "https://api.soarchain.com/soarchain/epochs", identifier: "day", want: "https://api.soarchain.com/soarchain/epochs/day"},
+		{endpoint: "https://api.soarchain.com/soarchain/epochs", identifier: "week", want: "https://api.soarchain.com/soarchain/epochs/week"},
+		{endpoint: "https://api.soarchain.com/soarchain/epochs/", identifier: "week", want: "https://api.soarchain.com/soarchain/epochs/week"},
+		{endpoint: "https://api.soarchain.com/soarchain/epochs", identifier: "", wantErr: true},
+		{endpoint: "https://api.soarchain.com/soarchain/epochs", identifier: "../admin", wantErr: true},
+		{endpoint: "https://api.soarchain.com/soarchain/epochs", identifier: "day?x=1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := epochURL(tt.endpoint, tt.identifier)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("epochURL(%q, %q) = %q, %v; want %q, error %v", tt.endpoint, tt.identifier, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGetCurrentEpochRequestsIdentifierPath(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		fmt.Fprintf(w, `{"epoch":{"duration":"86400s","current_epoch":"1","current_epoch_start_time":%q}}`,
+			time.Now().UTC().Format(time.RFC3339Nano))
+	}))
+	defer srv.Close()
+
+	for _, identifier := range []string{"day", "week"} {
+		info, err := getCurrentEpoch(srv.Client(), srv.URL+"/soarchain/epochs", identifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The identifier is taken from the request when the API omits it
+		if info.Identifier != identifier {
+			t.Errorf("identifier %q, want %q", info.Identifier, identifier)
+		}
+	}
+	if want := []string{"/soarchain/epochs/day", "/soarchain/epochs/week"}; fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+}
+
+// TestDecreasingEpochNumbers fetches an epoch number lower than the last
+// one, as after a chain reset, and stores earnings in it.
+func TestDecreasingEpochNumbers(t *testing.T) {
+	oldStart := time.Date(2024, 12, 5, 9, 0, 0, 0, time.UTC)
+	newStart := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	responses := []struct {
+		epoch int64
+		start time.Time
+	}{
+		{40, oldStart.AddDate(0, 0, 37)},
+		{3, newStart}, // the reset chain reuses epoch 3
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		next := responses[0]
+		if len(responses) > 1 {
+			responses = responses[1:]
+		}
+		mu.Unlock()
+		fmt.Fprintf(w, `{"epoch":{"identifier":"day","duration":"86400s","current_epoch":"%d","current_epoch_start_time":%q}}`,
+			next.epoch, next.start.Format(time.RFC3339Nano))
+	}))
+	defer srv.Close()
+
+	db := openTestDB(t)
+	br, err := newBlockReader(loadTestConfig(t, `{"epoch_endpoint": "`+srv.URL+`"}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Earnings of the old chain's epoch 3
+	old := EpochInfo{Identifier: "day", CurrentEpoch: 3, CurrentEpochStart: oldStart, Duration: 24 * time.Hour}
+	if err := upsertEpochEarnings(db, "SoLalice", 100, old); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+	if _, err := br.currentEpoch("day", logger); err != nil {
+		t.Fatal(err)
+	}
+	current, err := br.currentEpoch("day", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.CurrentEpoch != 3 || !current.CurrentEpochStart.Equal(newStart) {
+		t.Fatalf("current epoch %d from %s, want 3 from %s", current.CurrentEpoch, current.CurrentEpochStart, newStart)
+	}
+	if !strings.Contains(logs.String(), "Warning: day epoch number went back from 40 to 3") {
+		t.Errorf("log %q doesn't warn about the decreasing epoch number", logs.String())
+	}
+
+	// The new epoch 3 is stored apart from the old one
+	if err := upsertEpochEarnings(db, "SoLalice", 50, current); err != nil {
+		t.Fatal(err)
+	}
+	var rows []models.EpochEarnings
+	if err := db.Order("start_time").Find(&rows, "epoch_number = 3").Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].TotalEarnings != 100 || !rows[1].StartTime.Equal(newStart) || rows[1].TotalEarnings != 50 {
+		t.Errorf("epoch 3 rows %+v, want the old one with 100 and the new one with 50", rows)
+	}
+}
@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EarningsRecord is one client earnings entry loaded outside the WebSocket
+// feed, e.g. historical data or test fixtures. Earnings uses the chain's
+// coin format ("1500usoar", or a bare "1500" in the expected denom) and
+// EpochNumber is the primary epoch Timestamp falls in.
+type EarningsRecord struct {
+	Address       string    `json:"address"`
+	PubKey        string    `json:"pubkey"`
+	SolanaAddress string    `json:"solanaAddress"`
+	Earnings      string    `json:"earnings"`
+	Timestamp     time.Time `json:"timestamp"`
+	EpochNumber   int64     `json:"epochNumber"`
+}
+
+// ErrInvalidEarnings is wrapped by IngestEarnings errors caused by the
+// record itself rather than by storing it
+var ErrInvalidEarnings = errors.New("invalid earnings record")
+
+// CurrentEpochs returns the current epoch of every configured identifier,
+// in EpochIdentifiers order, or none when epoch aggregation is disabled.
+func (br *BlockReader) CurrentEpochs(logger *log.Logger) ([]EpochInfo, error) {
+	if !br.EpochAggregation {
+		return nil, nil
+	}
+	epochs := make([]EpochInfo, 0, len(br.EpochIdentifiers))
+	for _, identifier := range br.EpochIdentifiers {
+		epochInfo, err := br.currentEpoch(identifier, logger)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s epoch info: %w", identifier, err)
+		}
+		epochs = append(epochs, epochInfo)
+	}
+	return epochs, nil
+}
+
+// IngestEarnings stores one record the same way as earnings read from the
+// chain, aggregating it into the epochs derived from current as returned by
+// CurrentEpochs (EpochNumber is only checked when there are any). Records
+// that can't be stored as given, e.g. whose timestamp isn't in their
+// EpochNumber, fail with ErrInvalidEarnings.
+func (br *BlockReader) IngestEarnings(record EarningsRecord, current []EpochInfo) error {
+	if record.Address == "" {
+		return fmt.Errorf("%w: missing address", ErrInvalidEarnings)
+	}
+	if record.Timestamp.IsZero() {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidEarnings)
+	}
+	earningsValue, err := br.parseEarnings(coins{record.Earnings})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEarnings, err)
+	}
+
+	timestamp := record.Timestamp.UTC()
+	epochs := make([]EpochInfo, 0, len(current))
+	for _, info := range current {
+		epochs = append(epochs, epochAt(info, timestamp))
+	}
+	if len(epochs) > 0 && epochs[0].CurrentEpoch != record.EpochNumber {
+		return fmt.Errorf("%w: timestamp %s is in %s epoch %d, not %d", ErrInvalidEarnings,
+			timestamp.Format(time.RFC3339), epochs[0].Identifier, epochs[0].CurrentEpoch, record.EpochNumber)
+	}
+
+	data := clientData{
+		Address:       record.Address,
+		PubKey:        record.PubKey,
+		Earnings:      coins{record.Earnings},
+		SolanaAddress: record.SolanaAddress,
+	}
+	var created bool
+	err = br.transaction(log.Default(), "earnings of client "+record.Address, func(tx *gorm.DB) error {
+		created, err = storeClientEarnings(tx, data, record.SolanaAddress, earningsValue, epochs, timestamp, br.StatusDownAfter)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if created {
+		br.announceNewClient(newClientEvent(data, record.SolanaAddress, timestamp))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/metrics"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+// NewClientEvent announces a client seen for the first time.
+type NewClientEvent struct {
+	Type          string    `json:"type"` // always "new_client"
+	Address       string    `json:"address"`
+	PubKey        string    `json:"pubkey"`
+	SolanaAddress string    `json:"solanaAddress"`
+	FirstSeen     time.Time `json:"firstSeen"`
+}
+
+func newClientEvent(data clientData, solanaAddress string, timestamp time.Time) NewClientEvent {
+	return NewClientEvent{
+		Type:          "new_client",
+		Address:       data.Address,
+		PubKey:        data.PubKey,
+		SolanaAddress: solanaAddress,
+		FirstSeen:     timestamp,
+	}
+}
+
+// announceNewClient counts and logs a new client and, when configured, posts
+// the event to the new client webhook. The webhook is called inline, so a
+// slow receiver holds up ingestion for at most the HTTP timeout, and
+// ReadBlocks doesn't return while a post is still in flight.
+func (br *BlockReader) announceNewClient(event NewClientEvent) {
+	metrics.NewClients.Inc()
+	br.Log.Info("new client", "address", event.Address, "pubkey", event.PubKey, "solanaAddress", event.SolanaAddress)
+
+	if br.NewClientWebhookURL == "" {
+		return
+	}
+	if err := br.postWebhook(br.NewClientWebhookURL, event); err != nil {
+		br.Log.Warn("new client webhook failed", "address", event.Address, "error", err)
+	}
+}
+
+// postWebhook posts payload as JSON to url.
+func (br *BlockReader) postWebhook(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	resp, err := br.HTTPClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// maxFinalizedEpochs bounds how many ended epochs one epoch refresh reports,
+// in case the observer was stalled for a long time
+const maxFinalizedEpochs = 10
+
+// EpochFinalizedEvent reports the totals of an epoch that has ended.
+type EpochFinalizedEvent struct {
+	Type          string    `json:"type"` // always "epoch_finalized"
+	Identifier    string    `json:"identifier"`
+	EpochNumber   int64     `json:"epochNumber"`
+	StartTime     time.Time `json:"startTime"`
+	EndTime       time.Time `json:"endTime"`
+	TotalEarnings int64     `json:"totalEarnings,string"` // in the earnings denom
+	Participants  int64     `json:"participants"`
+}
+
+// finalizeEpochs announces the epochs from last, the previously fetched
+// epoch, up to but excluding current, which have ended.
+func (br *BlockReader) finalizeEpochs(last EpochInfo, current int64, logger *log.Logger) {
+	first := last.CurrentEpoch
+	if current-first > maxFinalizedEpochs {
+		first = current - maxFinalizedEpochs
+	}
+	for number := first; number < current; number++ {
+		epoch := last
+		if number != last.CurrentEpoch {
+			epoch = epochAt(last, last.CurrentEpochStart.Add(time.Duration(number-last.CurrentEpoch)*last.Duration))
+		}
+		event, err := br.epochFinalizedEvent(epoch)
+		if err != nil {
+			logger.Printf("Error totalling %s epoch %d: %v", epoch.Identifier, number, err)
+			continue
+		}
+		br.announceEpochFinalized(event)
+	}
+
+	// The price at the boundary values the epoch that just ended
+	if br.PriceProviderURL != "" && !br.DryRun && len(br.EpochIdentifiers) > 0 && last.Identifier == br.EpochIdentifiers[0] {
+		end := epochAt(last, last.CurrentEpochStart.Add(time.Duration(current-1-last.CurrentEpoch)*last.Duration)).End()
+		if err := br.recordPrice(end); err != nil {
+			logger.Printf("Error recording the %s price at the end of %s epoch %d: %v", br.TokenSymbol, last.Identifier, current-1, err)
+		}
+	}
+}
+
+// epochFinalizedEvent totals the earnings aggregated into epoch.
+func (br *BlockReader) epochFinalizedEvent(epoch EpochInfo) (EpochFinalizedEvent, error) {
+	var totals struct {
+		TotalEarnings int64
+		Participants  int64
+	}
+	err := br.DB.Model(&models.EpochEarnings{}).
+		Select("COALESCE(SUM(total_earnings), 0) AS total_earnings, COUNT(DISTINCT client_address) AS participants").
+		Where("identifier = ? AND epoch_number = ? AND start_time = ?",
+			epoch.Identifier, epoch.CurrentEpoch, epoch.CurrentEpochStart.Truncate(time.Microsecond)).
+		Scan(&totals).Error
+	if err != nil {
+		return EpochFinalizedEvent{}, err
+	}
+	return EpochFinalizedEvent{
+		Type:          "epoch_finalized",
+		Identifier:    epoch.Identifier,
+		EpochNumber:   epoch.CurrentEpoch,
+		StartTime:     epoch.CurrentEpochStart,
+		EndTime:       epoch.End(),
+		TotalEarnings: totals.TotalEarnings,
+		Participants:  totals.Participants,
+	}, nil
+}
+
+// announceEpochFinalized logs a finalized epoch and, when configured, posts
+// it to the epoch webhook. A dry run only logs it, leaving the posting to the
+// instance storing the earnings.
+func (br *BlockReader) announceEpochFinalized(event EpochFinalizedEvent) {
+	br.Log.Info("epoch finalized", "identifier", event.Identifier, "epochNumber", event.EpochNumber,
+		"totalEarnings", event.TotalEarnings, "participants", event.Participants)
+
+	if br.EpochWebhookURL == "" || br.DryRun {
+		return
+	}
+	if err := br.postWebhook(br.EpochWebhookURL, event); err != nil {
+		br.Log.Warn("epoch webhook failed", "identifier", event.Identifier, "epochNumber", event.EpochNumber, "error", err)
+	}
+}
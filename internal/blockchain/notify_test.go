@@ -0,0 +1,112 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEpochAdvancePostsFinalizedEpoch moves the fetched epoch from 5 to 6
+// and expects epoch 5's totals on the epoch webhook.
+func TestEpochAdvancePostsFinalizedEpoch(t *testing.T) {
+	start := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	var current atomic.Int64
+	current.Store(5)
+	epochAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Load()
+		fmt.Fprintf(w, `{"epoch":{"identifier":"day","duration":"86400s","current_epoch":"%d","current_epoch_start_time":%q}}`,
+			n, start.Add(time.Duration(n-5)*24*time.Hour).Format(time.RFC3339))
+	}))
+	defer epochAPI.Close()
+
+	events := make(chan EpochFinalizedEvent, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event EpochFinalizedEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decoding the webhook body: %v", err)
+		}
+		events <- event
+	}))
+	defer webhook.Close()
+
+	db := openTestDB(t)
+	br, err := newBlockReader(loadTestConfig(t, `{"epoch_endpoint": "`+epochAPI.URL+`", "epoch_webhook_url": "`+webhook.URL+`"}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	epoch5, err := br.currentEpoch("day", quietLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for wallet, amount := range map[string]int64{"SoLalice": 300, "SoLbob": 200} {
+		if err := upsertEpochEarnings(db, wallet, amount, epoch5); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current.Store(6)
+	if _, err := br.currentEpoch("day", quietLogger); err != nil {
+		t.Fatal(err)
+	}
+
+	// Finalizing runs before currentEpoch returns, so the event is already there
+	select {
+	case event := <-events:
+		want := EpochFinalizedEvent{
+			Type:          "epoch_finalized",
+			Identifier:    "day",
+			EpochNumber:   5,
+			StartTime:     start,
+			EndTime:       start.Add(24 * time.Hour),
+			TotalEarnings: 500,
+			Participants:  2,
+		}
+		if event.Type != want.Type || event.Identifier != want.Identifier || event.EpochNumber != want.EpochNumber ||
+			!event.StartTime.Equal(want.StartTime) || !event.EndTime.Equal(want.EndTime) ||
+			event.TotalEarnings != want.TotalEarnings || event.Participants != want.Participants {
+			t.Errorf("finalized %+v, want %+v", event, want)
+		}
+	default:
+		t.Fatal("no epoch finalized event when currentEpoch returned after the epoch advanced")
+	}
+
+	// Fetching the same epoch again finalizes nothing
+	if _, err := br.currentEpoch("day", quietLogger); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected second event %+v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestNewClientWebhookPostedInline expects the new client webhook to have
+// been called by the time announceNewClient returns, so shutdown can't
+// leave one behind.
+func TestNewClientWebhookPostedInline(t *testing.T) {
+	var posts atomic.Int64
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event NewClientEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decoding the webhook body: %v", err)
+		}
+		if event.Type != "new_client" || event.Address != "soar1new" {
+			t.Errorf("posted %+v", event)
+		}
+		posts.Add(1)
+	}))
+	defer webhook.Close()
+
+	br := &BlockReader{HTTPClient: webhook.Client(), NewClientWebhookURL: webhook.URL}
+	captureLog(br)
+	br.announceNewClient(newClientEvent(clientData{Address: "soar1new", PubKey: "pk"}, "SoLnew", time.Now().UTC()))
+	if n := posts.Load(); n != 1 {
+		t.Errorf("webhook called %d times when announceNewClient returned, want 1", n)
+	}
+}
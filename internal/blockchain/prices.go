@@ -0,0 +1,48 @@
+package blockchain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+// fetchPrice asks the price provider for the current USD price of the token.
+func (br *BlockReader) fetchPrice() (float64, error) {
+	resp, err := br.HTTPClient.Get(br.PriceProviderURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch price: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code from price provider: %d", resp.StatusCode)
+	}
+	var body struct {
+		USD *float64 `json:"usd"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to parse price: %w", err)
+	}
+	if body.USD == nil || *body.USD < 0 || math.IsInf(*body.USD, 0) || math.IsNaN(*body.USD) {
+		return 0, errors.New("price provider didn't return a non-negative 'usd' price")
+	}
+	return *body.USD, nil
+}
+
+// recordPrice stores the provider's current price as the price at t.
+func (br *BlockReader) recordPrice(t time.Time) error {
+	usd, err := br.fetchPrice()
+	if err != nil {
+		return err
+	}
+	return br.DB.Create(&models.PriceHistory{
+		Symbol:    br.TokenSymbol,
+		Timestamp: t.UTC(),
+		USD:       usd,
+	}).Error
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+)
+
+func TestFinalizeEpochsRecordsPrice(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"usd": 0.42}`)
+	}))
+	defer provider.Close()
+
+	cfg := loadTestConfig(t, fmt.Sprintf(`{"price_provider_url": %q, "epoch_identifiers": ["day", "week"]}`, provider.URL))
+	db := openTestDB(t)
+	br, err := newBlockReader(cfg, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := EpochInfo{Identifier: "day", Duration: 24 * time.Hour, CurrentEpoch: 10, CurrentEpochStart: start}
+	week := EpochInfo{Identifier: "week", Duration: 7 * 24 * time.Hour, CurrentEpoch: 2, CurrentEpochStart: start}
+
+	// Epochs 10 and 11 ended; the price is taken at the end of 11. Ended
+	// weeks don't record another price.
+	br.finalizeEpochs(day, 12, quietLogger)
+	br.finalizeEpochs(week, 3, quietLogger)
+
+	var prices []models.PriceHistory
+	if err := db.Find(&prices).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 1 {
+		t.Fatalf("recorded %d prices, want 1", len(prices))
+	}
+	if want := start.Add(48 * time.Hour); prices[0].Symbol != "SOAR" || prices[0].USD != 0.42 || !prices[0].Timestamp.Equal(want) {
+		t.Errorf("recorded %+v, want SOAR at 0.42 USD at %s", prices[0], want)
+	}
+}
+
+func TestFetchPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    float64
+		wantErr bool
+	}{
+		{"price", http.StatusOK, `{"usd": 1.25}`, 1.25, false},
+		{"zero", http.StatusOK, `{"usd": 0}`, 0, false},
+		{"missing", http.StatusOK, `{"eur": 1}`, 0, true},
+		{"negative", http.StatusOK, `{"usd": -1}`, 0, true},
+		{"not json", http.StatusOK, `1.25`, 0, true},
+		{"error status", http.StatusTooManyRequests, `{"usd": 1}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer provider.Close()
+
+			br := &BlockReader{HTTPClient: provider.Client(), PriceProviderURL: provider.URL}
+			got, err := br.fetchPrice()
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("fetchPrice = %v, %v; want %v (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package blockchain
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Postgres error codes of transactions aborted by a concurrent one, which
+// succeed when simply run again. The observer's transactions run at the
+// default READ COMMITTED isolation, where concurrent writers wait on each
+// other's row locks instead of failing, so in practice only deadlocks are
+// retried; serialization failures only occur on databases whose
+// default_transaction_isolation is REPEATABLE READ or SERIALIZABLE.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// isRetryable reports whether err aborted a transaction because of a
+// serialization failure or deadlock.
+func isRetryable(err error) bool {
+	var sqlErr interface{ SQLState() string }
+	if !errors.As(err, &sqlErr) {
+		return false
+	}
+	switch sqlErr.SQLState() {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// transaction runs fn in a transaction, running it again in a new one when
+// it fails with a serialization failure or deadlock, up to TxRetryAttempts
+// attempts with a doubling backoff. fn must be safe to run more than once.
+// what describes the transaction in the retry log lines.
+func (br *BlockReader) transaction(logger *log.Logger, what string, fn func(tx *gorm.DB) error) error {
+	attempts := br.TxRetryAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := br.TxRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := br.DB.Transaction(fn)
+		if err == nil || !isRetryable(err) || attempt >= attempts {
+			return err
+		}
+		logger.Printf("Retrying %s after %s (attempt %d of %d): %v", what, backoff, attempt+1, attempts, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
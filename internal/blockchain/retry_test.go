@@ -0,0 +1,95 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"gorm.io/gorm"
+)
+
+// sqlStateError stands in for a driver error carrying a Postgres SQLSTATE
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{sqlStateError(sqlStateDeadlockDetected), true},
+		{sqlStateError(sqlStateSerializationFailure), true},
+		{fmt.Errorf("storing earnings: %w", sqlStateError(sqlStateDeadlockDetected)), true},
+		{sqlStateError("23505"), false}, // unique violation
+		{errors.New("connection refused"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestTransactionRetriesDeadlocks(t *testing.T) {
+	db := openTestDB(t)
+	br := &BlockReader{DB: db, TxRetryAttempts: 3}
+
+	attempts := 0
+	err := br.transaction(quietLogger, "test", func(tx *gorm.DB) error {
+		attempts++
+		if err := tx.Create(&models.ProcessedTx{Hash: fmt.Sprintf("TX%d", attempts)}).Error; err != nil {
+			return err
+		}
+		if attempts == 1 {
+			return sqlStateError(sqlStateDeadlockDetected)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("ran %d attempts, want 2", attempts)
+	}
+
+	// Only the second attempt committed
+	var hashes []string
+	if err := db.Model(&models.ProcessedTx{}).Pluck("hash", &hashes).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 1 || hashes[0] != "TX2" {
+		t.Errorf("stored txs %v, want only TX2", hashes)
+	}
+}
+
+func TestTransactionGivesUp(t *testing.T) {
+	br := &BlockReader{DB: openTestDB(t), TxRetryAttempts: 3}
+
+	tests := []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		{"retryable error on every attempt", sqlStateError(sqlStateSerializationFailure), 3},
+		{"other error", errors.New("constraint violated"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := br.transaction(quietLogger, "test", func(tx *gorm.DB) error {
+				attempts++
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("ran %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
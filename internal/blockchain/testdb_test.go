@@ -0,0 +1,58 @@
+package blockchain
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB returns an empty sqlite database with the observer's schema.
+// Writers take the lock when their transaction begins and wait for each
+// other, so concurrent tests behave as on Postgres rather than failing with
+// "database is locked".
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "observer.db") + "?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&models.Client{},
+		&models.ClientEarning{},
+		&models.EpochEarnings{},
+		&models.EpochArchive{},
+		&models.ProcessedTx{},
+		&models.StatusTransition{},
+		&models.PriceHistory{},
+	); err != nil {
+		t.Fatalf("migrating test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// loadTestConfig loads configJSON as config.json, with its defaults
+// applied.
+func loadTestConfig(t *testing.T, configJSON string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(configJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	return cfg
+}
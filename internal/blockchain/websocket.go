@@ -1,25 +1,144 @@
 package blockchain
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"math"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Soar-Robotics/SoarchainObserver/internal/blockchain/types"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/config"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/metrics"
 	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/utils"
 	"github.com/gorilla/websocket"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// challengeAction is the message.action of the txs whose earnings are recorded
+const challengeAction = "runner_challenge"
+
+// subscriptionID is the JSON-RPC id of the subscribe request
+const subscriptionID = 1
+
+// subscribeAckTimeout bounds the wait for the node's subscribe response
+const subscribeAckTimeout = 10 * time.Second
+
+// ErrShutdown is returned when a reconnection attempt is abandoned because
+// the reader's context was cancelled.
+var ErrShutdown = errors.New("reconnection aborted due to shutdown")
+
 // BlockReader manages the WebSocket connection and DB
+//
+// Locking discipline: the exported configuration fields are set before
+// ReadBlocks starts and are read-only afterwards. Everything that changes
+// while the reader runs is shared between the read loop, the idle monitor,
+// the shutdown watcher and the HTTP handlers, and is guarded as follows:
+//   - conn is replaced by Connect and closed from other goroutines; always
+//     go through currentConn/closeConn, which hold connMu.
+//   - lastMessageAt and connectedAt are atomics.
+//   - stats is guarded by statsMu, the epoch cache by epochMu.
+//
+// No two of these locks are ever held at the same time.
 type BlockReader struct {
-	Conn *websocket.Conn
-	URL  string
-	DB   *gorm.DB
+	URL    string
+	DB     *gorm.DB
+	Dialer *websocket.Dialer
+	Header http.Header // extra headers for the WebSocket upgrade
+
+	// HTTPClient is used for REST calls to the chain (epoch info)
+	HTTPClient *http.Client
+
+	// MaxIdleDuration, when non-zero, makes ReadBlocks drop the connection if
+	// nothing has been received for that long.
+	MaxIdleDuration time.Duration
+
+	connMu sync.Mutex
+	conn   *websocket.Conn // guarded by connMu
+
+	lastMessageAt atomic.Int64 // unix nanos of the last received message
+	connectedAt   atomic.Int64 // unix nanos of the last successful Connect
+
+	statsMu sync.Mutex
+	stats   ReconnectStats
+
+	// EpochStaleAfter is how long epoch fetches may keep failing before
+	// EpochStale reports the cached epoch as stale.
+	EpochStaleAfter time.Duration
+
+	// EpochIdentifiers are the epochs ("day", "week") earnings are aggregated into
+	EpochIdentifiers []string
+
+	// EpochAggregation enables aggregating earnings into epochs; without it
+	// the epoch API isn't called while ingesting
+	EpochAggregation bool
+
+	// EpochEndpoint is the base URL of the epoch API
+	EpochEndpoint string
+
+	// ExpectedDenom is the denom earnings must be paid in, e.g. "usoar"
+	ExpectedDenom string
+
+	// SolanaAddressKeys are the sources tried, in order, for a client's
+	// solana address
+	SolanaAddressKeys []string
+
+	// StatusDownAfter is how long after its last challenge a miner is
+	// considered Down, used to record status transitions
+	StatusDownAfter time.Duration
+
+	// MaxMessageBytes is the read limit of the WebSocket connection
+	MaxMessageBytes int64
+
+	// NewClientWebhookURL, if set, receives a POST for every new client
+	NewClientWebhookURL string
+
+	// EpochWebhookURL, if set, receives a POST for every finalized epoch
+	EpochWebhookURL string
+
+	// PriceProviderURL, if set, is asked for the USD price of TokenSymbol
+	// at the end of every epoch of the primary identifier
+	PriceProviderURL string
+	TokenSymbol      string
+
+	// TxRetryAttempts and TxRetryBackoff bound the retries of earnings
+	// transactions aborted by a serialization failure or deadlock
+	TxRetryAttempts int
+	TxRetryBackoff  time.Duration
+
+	// DryRun makes processEvents log the earnings it would store instead of
+	// writing them
+	DryRun bool
+
+	// Log receives the structured log lines, at the configured log_level
+	Log *slog.Logger
+
+	epochMu sync.Mutex
+	epochs  map[string]*EpochStatus // keyed by identifier
+
+	// the block time last looked up by eventTime
+	lastBlockHeight int64
+	lastBlockTime   time.Time
+}
+
+// ReconnectStats summarises how often the WebSocket connection had to be
+// re-established.
+type ReconnectStats struct {
+	Reconnections   int64
+	LastReconnectAt time.Time
+	LastError       string
+	LastErrorAt     time.Time
 }
 
 // EpochInfo holds relevant fields from the Soarchain epoch response.
@@ -28,13 +147,25 @@ type EpochInfo struct {
 	Duration          time.Duration
 	CurrentEpoch      int64
 	CurrentEpochStart time.Time
+	// CurrentEpochEnd is the end reported by the chain, zero when the epoch
+	// API doesn't provide one
+	CurrentEpochEnd time.Time
 }
 
-// NewBlockReader initializes a BlockReader with a WebSocket connection to `url`.
-func NewBlockReader(url string, db *gorm.DB) (*BlockReader, error) {
-	br := &BlockReader{
-		URL: url,
-		DB:  db, // Assign the db parameter
+// End returns the chain-reported end of the current epoch, falling back to
+// its start plus the epoch duration.
+func (e EpochInfo) End() time.Time {
+	if !e.CurrentEpochEnd.IsZero() {
+		return e.CurrentEpochEnd
+	}
+	return e.CurrentEpochStart.Add(e.Duration)
+}
+
+// NewBlockReader initializes a BlockReader with a WebSocket connection to the configured RPC endpoint.
+func NewBlockReader(cfg *config.Config, db *gorm.DB) (*BlockReader, error) {
+	br, err := newBlockReader(cfg, db)
+	if err != nil {
+		return nil, err
 	}
 	if err := br.Connect(); err != nil {
 		return nil, err
@@ -42,25 +173,75 @@ func NewBlockReader(url string, db *gorm.DB) (*BlockReader, error) {
 	return br, nil
 }
 
+// newBlockReader builds a BlockReader without connecting it.
+func newBlockReader(cfg *config.Config, db *gorm.DB) (*BlockReader, error) {
+	dialer, err := newDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	for name, value := range cfg.RPCHeaders {
+		header.Set(name, value)
+	}
+	br := &BlockReader{
+		URL:             cfg.RPCEndpoint,
+		DB:              db, // Assign the db parameter
+		Dialer:          dialer,
+		Header:          header,
+		HTTPClient:      httpClient,
+		MaxIdleDuration: cfg.MaxIdleDuration.Duration,
+		EpochStaleAfter: cfg.EpochStaleAfter.Duration,
+
+		EpochIdentifiers: cfg.EpochIdentifiers,
+		EpochEndpoint:    cfg.EpochEndpoint,
+		EpochAggregation: cfg.EpochAggregation(),
+		ExpectedDenom:    cfg.ExpectedDenom,
+
+		SolanaAddressKeys: cfg.SolanaAddressKeys,
+		StatusDownAfter:   cfg.StatusDownAfter.Duration,
+
+		MaxMessageBytes:     cfg.MaxMessageBytes,
+		NewClientWebhookURL: cfg.NewClientWebhookURL,
+		EpochWebhookURL:     cfg.EpochWebhookURL,
+		PriceProviderURL:    cfg.PriceProviderURL,
+		TokenSymbol:         cfg.TokenSymbol(),
+		DryRun:              cfg.DryRun,
+		TxRetryAttempts:     cfg.TxRetryAttempts,
+		TxRetryBackoff:      cfg.TxRetryBackoff.Duration,
+		Log:                 utils.GetStructuredLoggerAt(utils.ParseLogLevel(cfg.LogLevel)),
+
+		epochs: make(map[string]*EpochStatus, len(cfg.EpochIdentifiers)),
+	}
+	return br, nil
+}
+
 // Connect dials the WebSocket and subscribes to runner_challenge
 func (br *BlockReader) Connect() error {
 	log.Printf("Connecting to WebSocket URL: %s", br.URL)
-	conn, _, err := websocket.DefaultDialer.Dial(br.URL, nil)
+	if len(br.Header) > 0 {
+		log.Printf("Using RPC headers: %s", redactHeader(br.Header))
+	}
+	conn, _, err := br.Dialer.Dial(br.URL, br.Header)
 	if err != nil {
 		log.Printf("Failed to connect to WebSocket: %v", err)
 		return err
 	}
 	log.Println("Successfully connected to WebSocket")
+	conn.SetReadLimit(br.MaxMessageBytes)
 
 	// Subscription message for runner_challenge Tx events
-	subscribeMsg := `{
+	subscribeMsg := fmt.Sprintf(`{
         "jsonrpc": "2.0",
         "method": "subscribe",
-        "id": 1,
+        "id": %d,
         "params": {
-            "query": "tm.event='Tx' AND message.action='runner_challenge'"
+            "query": "tm.event='Tx' AND message.action='%s'"
         }
-    }`
+    }`, subscriptionID, challengeAction)
 
 	if err := conn.WriteMessage(websocket.TextMessage, []byte(subscribeMsg)); err != nil {
 		log.Printf("Failed to send subscription message: %v", err)
@@ -69,21 +250,160 @@ func (br *BlockReader) Connect() error {
 	}
 	log.Println("Subscription message sent successfully")
 
-	br.Conn = conn
+	// The first response acknowledges the subscription; without checking it
+	// a rejected subscription would look connected but never receive events
+	conn.SetReadDeadline(time.Now().Add(subscribeAckTimeout))
+	_, ack, err := conn.ReadMessage()
+	if err != nil {
+		log.Printf("Failed to read subscription ack: %v", err)
+		conn.Close()
+		return err
+	}
+	conn.SetReadDeadline(time.Time{})
+	if err := checkSubscribeAck(ack); err != nil {
+		log.Printf("Subscription rejected: %v", err)
+		conn.Close()
+		return err
+	}
+	log.Printf("Subscription acknowledged: %s", ack)
+
+	br.connMu.Lock()
+	br.conn = conn
+	br.connMu.Unlock()
+	br.connectedAt.Store(time.Now().UnixNano())
+	return nil
+}
+
+// checkSubscribeAck validates the node's JSON-RPC response to the subscribe
+// request.
+func checkSubscribeAck(message []byte) error {
+	var ack struct {
+		ID     json.RawMessage `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    string `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(message, &ack); err != nil {
+		return fmt.Errorf("invalid subscription ack: %w", err)
+	}
+	if string(ack.ID) != strconv.Itoa(subscriptionID) {
+		return fmt.Errorf("unexpected subscription ack id %s", ack.ID)
+	}
+	if ack.Error != nil {
+		return fmt.Errorf("subscription error %d: %s %s", ack.Error.Code, ack.Error.Message, ack.Error.Data)
+	}
+	if ack.Result == nil {
+		return errors.New("subscription ack has no result")
+	}
 	return nil
 }
 
-// ReadBlocks continuously reads from the WebSocket, processes messages, and handles reconnections
-func (br *BlockReader) ReadBlocks(logger *log.Logger) {
-	defer br.Conn.Close()
+// currentConn returns the active WebSocket connection.
+func (br *BlockReader) currentConn() *websocket.Conn {
+	br.connMu.Lock()
+	defer br.connMu.Unlock()
+	return br.conn
+}
+
+// closeConn closes the active WebSocket connection, which makes a pending
+// ReadMessage return an error.
+func (br *BlockReader) closeConn() {
+	br.connMu.Lock()
+	defer br.connMu.Unlock()
+	if br.conn != nil {
+		br.conn.Close()
+	}
+}
+
+// ReconnectStats returns a snapshot of the reconnection counters.
+func (br *BlockReader) ReconnectStats() ReconnectStats {
+	br.statsMu.Lock()
+	defer br.statsMu.Unlock()
+	return br.stats
+}
+
+// LastMessageAt returns when the last WebSocket message was received,
+// or the zero time if none has arrived yet.
+func (br *BlockReader) LastMessageAt() time.Time {
+	nanos := br.lastMessageAt.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
+
+// minIdleCheckInterval is the shortest interval monitorIdle checks at
+const minIdleCheckInterval = 100 * time.Millisecond
+
+// monitorIdle closes the current connection when no message has arrived within
+// MaxIdleDuration, which makes ReadBlocks go through the reconnection path.
+// This catches half-open connections that never report a read error.
+func (br *BlockReader) monitorIdle(ctx context.Context, logger *log.Logger) {
+	interval := br.MaxIdleDuration / 2
+	if interval < minIdleCheckInterval {
+		interval = minIdleCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Measure from whichever happened last so a fresh connection gets a
+		// full idle window before it can be considered stale.
+		last := br.lastMessageAt.Load()
+		if connected := br.connectedAt.Load(); connected > last {
+			last = connected
+		}
+		idle := time.Since(time.Unix(0, last))
+		if idle > br.MaxIdleDuration {
+			logger.Printf("No message received for %s, forcing reconnect", idle.Round(time.Second))
+			br.closeConn()
+		}
+	}
+}
+
+// ReadBlocks continuously reads from the WebSocket, processes messages, and handles reconnections.
+// It returns once ctx is cancelled.
+func (br *BlockReader) ReadBlocks(ctx context.Context, logger *log.Logger) {
+	// Closing the connection unblocks a pending ReadMessage on shutdown
+	go func() {
+		<-ctx.Done()
+		br.closeConn()
+	}()
+
+	if br.MaxIdleDuration > 0 {
+		go br.monitorIdle(ctx, logger)
+	}
+
 	for {
 		// Read a new message
-		_, message, err := br.Conn.ReadMessage()
+		_, message, err := br.currentConn().ReadMessage()
 		if err != nil {
-			logger.Printf("Error reading message: %v", err)
-			br.handleReconnection(logger)
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, websocket.ErrReadLimit) {
+				metrics.MalformedMessages.WithLabelValues("oversized").Inc()
+				logger.Printf("Message exceeds %d bytes, dropping the connection", br.MaxMessageBytes)
+			} else {
+				logger.Printf("Error reading message: %v", err)
+			}
+			if err := br.handleReconnection(ctx, logger); errors.Is(err, ErrShutdown) {
+				return
+			}
 			continue
 		}
+		if ctx.Err() != nil {
+			return
+		}
+		br.lastMessageAt.Store(time.Now().UnixNano())
 		log.Println("Received message:", string(message))
 		// Process the message
 		br.processMessage(message, logger)
@@ -91,32 +411,59 @@ func (br *BlockReader) ReadBlocks(logger *log.Logger) {
 	}
 }
 
-// handleReconnection attempts to reconnect after an error
-func (br *BlockReader) handleReconnection(logger *log.Logger) {
+// handleReconnection attempts to reconnect after an error.
+// It returns ErrShutdown as soon as ctx is cancelled.
+func (br *BlockReader) handleReconnection(ctx context.Context, logger *log.Logger) error {
 	logger.Println("Attempting to reconnect...")
 	for {
-		time.Sleep(5 * time.Second)
+		select {
+		case <-ctx.Done():
+			logger.Println(ErrShutdown)
+			return ErrShutdown
+		case <-time.After(5 * time.Second):
+		}
 		if err := br.Connect(); err != nil {
 			logger.Printf("Reconnection failed: %v", err)
+			metrics.ReconnectionFailures.Inc()
+			br.statsMu.Lock()
+			br.stats.LastError = err.Error()
+			br.stats.LastErrorAt = time.Now().UTC()
+			br.statsMu.Unlock()
 			continue
 		}
+		// The shutdown watcher may already have closed the previous connection
+		if ctx.Err() != nil {
+			br.closeConn()
+			logger.Println(ErrShutdown)
+			return ErrShutdown
+		}
 		logger.Println("Reconnected successfully")
-		break
+		metrics.Reconnections.Inc()
+		br.statsMu.Lock()
+		br.stats.Reconnections++
+		br.stats.LastReconnectAt = time.Now().UTC()
+		br.statsMu.Unlock()
+		return nil
 	}
 }
 
-// getCurrentEpoch fetches and parses the current epoch info from Soarchain
-func getCurrentEpoch() (EpochInfo, error) {
+// getCurrentEpoch fetches and parses the current info of the epoch with the
+// given identifier ("day", "week") from the epoch API at endpoint
+func getCurrentEpoch(client *http.Client, endpoint, identifier string) (EpochInfo, error) {
 	var epochInfo EpochInfo
 
-	resp, err := http.Get("https://api.mainnet.soarchain.com/soarchain/epoch/day")
+	requestURL, err := epochURL(endpoint, identifier)
+	if err != nil {
+		return epochInfo, err
+	}
+	resp, err := client.Get(requestURL)
 	if err != nil {
 		return epochInfo, fmt.Errorf("failed to fetch epoch info: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return epochInfo, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return epochInfo, newEpochAPIError(resp)
 	}
 
 	// Expected structure (an example):
@@ -127,6 +474,7 @@ func getCurrentEpoch() (EpochInfo, error) {
 	//     "duration": "86400s",
 	//     "current_epoch": "33",
 	//     "current_epoch_start_time": "2025-01-16T09:04:54.532413149Z",
+	//     "current_epoch_end_time": "2025-01-17T09:04:54.532413149Z", // optional
 	//     ...
 	//   }
 	// }
@@ -138,6 +486,7 @@ func getCurrentEpoch() (EpochInfo, error) {
 			Duration              string `json:"duration"`
 			CurrentEpoch          string `json:"current_epoch"`
 			CurrentEpochStartTime string `json:"current_epoch_start_time"`
+			CurrentEpochEndTime   string `json:"current_epoch_end_time"`
 		} `json:"epoch"`
 	}
 
@@ -158,27 +507,75 @@ func getCurrentEpoch() (EpochInfo, error) {
 	}
 
 	// Convert duration string (e.g. "86400s") to time.Duration
-	durStr := raw.Epoch.Duration
-	if !strings.HasSuffix(durStr, "s") {
-		return epochInfo, fmt.Errorf("epoch duration does not end with 's': %s", durStr)
-	}
-	durVal, err := time.ParseDuration(durStr)
+	durVal, err := parseEpochDuration(raw.Epoch.Duration)
 	if err != nil {
+		log.Printf("Unparseable epoch duration %q", raw.Epoch.Duration)
 		return epochInfo, fmt.Errorf("failed to parse epoch duration: %w", err)
 	}
 
 	epochInfo.Identifier = raw.Epoch.Identifier
+	if epochInfo.Identifier == "" {
+		epochInfo.Identifier = identifier
+	}
 	epochInfo.Duration = durVal
 	epochInfo.CurrentEpoch = epochNum
 	epochInfo.CurrentEpochStart = epochStart
 
+	// Not every chain version reports the end; End() computes it otherwise
+	if raw.Epoch.CurrentEpochEndTime != "" {
+		epochEnd, err := time.Parse(time.RFC3339Nano, raw.Epoch.CurrentEpochEndTime)
+		if err != nil {
+			return epochInfo, fmt.Errorf("failed to parse current_epoch_end_time: %w", err)
+		}
+		if !epochEnd.After(epochStart) {
+			return epochInfo, fmt.Errorf("current_epoch_end_time %s is not after current_epoch_start_time", raw.Epoch.CurrentEpochEndTime)
+		}
+		epochInfo.CurrentEpochEnd = epochEnd
+	}
+
 	return epochInfo, nil
 }
 
+// parseEpochDuration accepts the chain's "86400s" form as well as any Go
+// duration ("24h", "1440m") and a bare number of seconds ("86400").
+func parseEpochDuration(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		raw = strconv.FormatFloat(seconds, 'f', -1, 64) + "s"
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("epoch duration must be positive: %q", raw)
+	}
+	return d, nil
+}
+
+// epochAt returns the epoch that contains t, derived from the current epoch
+// and the fixed epoch duration.
+func epochAt(current EpochInfo, t time.Time) EpochInfo {
+	epoch := current
+	offset := t.Sub(current.CurrentEpochStart)
+	n := int64(offset / current.Duration)
+	if offset < 0 && offset%current.Duration != 0 {
+		n-- // round towards the earlier epoch
+	}
+	epoch.CurrentEpoch = current.CurrentEpoch + n
+	epoch.CurrentEpochStart = current.CurrentEpochStart.Add(time.Duration(n) * current.Duration)
+	if n != 0 {
+		// The chain-reported end only applies to the fetched epoch
+		epoch.CurrentEpochEnd = time.Time{}
+	}
+	return epoch
+}
+
 // processMessage parses the raw message, extracts clients data, upserts DB rows, etc.
 func (br *BlockReader) processMessage(message []byte, logger *log.Logger) {
 	var msg map[string]interface{}
 	if err := json.Unmarshal(message, &msg); err != nil {
+		metrics.MalformedMessages.WithLabelValues("invalid_json").Inc()
 		logger.Printf("Error parsing message: %v", err)
 		return
 	}
@@ -187,156 +584,509 @@ func (br *BlockReader) processMessage(message []byte, logger *log.Logger) {
 	if !ok {
 		return
 	}
-	events, ok := result["events"].(map[string]interface{})
+	rawEvents, ok := result["events"].(map[string]interface{})
 	if !ok {
 		return
 	}
+	events := toEventMap(rawEvents)
+
+	// no client_data in this event, skip
+	if len(events["message.client_data"]) == 0 {
+		return
+	}
+
+	// Fetch the current epochs from Soarchain (only once per message), unless
+	// epoch aggregation is disabled.
+	// You could also fetch them once per block or on a timer, depending on performance needs.
+	epochs, err := br.CurrentEpochs(logger)
+	if err != nil {
+		logger.Printf("Skipping message: %v", err)
+		return
+	}
+
+	// Record the earnings at the block time, so messages delivered late
+	// still land in the right period and epoch
+	timestamp := br.eventTime(events, logger)
+	for i := range epochs {
+		epochs[i] = epochAt(epochs[i], timestamp)
+	}
+
+	br.processEvents(events, epochs, timestamp, logger)
+}
+
+// toEventMap converts the decoded "events" object of a Tendermint event
+// message into a map of string lists.
+func toEventMap(rawEvents map[string]interface{}) map[string][]string {
+	events := make(map[string][]string, len(rawEvents))
+	for key, rawValues := range rawEvents {
+		values, ok := rawValues.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, v := range values {
+			if str, ok := v.(string); ok {
+				events[key] = append(events[key], str)
+			}
+		}
+	}
+	return events
+}
 
+// errTxProcessed rolls back a tx found to have been stored concurrently
+var errTxProcessed = errors.New("tx already processed")
+
+// processEvents stores the client earnings carried by the events of one
+// runner_challenge tx. It is shared by the live WebSocket path and backfill;
+// timestamp is the time recorded for the earnings and epochs the epochs (one
+// per identifier) they are aggregated into. Txs whose hash was already stored
+// are skipped.
+func (br *BlockReader) processEvents(events map[string][]string, epochs []EpochInfo, timestamp time.Time, logger *log.Logger) {
 	// 1) Retrieve list of client_data from events
-	clientDataList, ok := events["message.client_data"].([]interface{})
-	if !ok {
-		// no client_data in this event, skip
+	clientDataList := events["message.client_data"]
+	if len(clientDataList) == 0 {
 		return
 	}
 
-	// 2) Retrieve the corresponding solana_address from events if it’s in the same subEvent
-	solanaAddressList, _ := events["solana_address"].([]interface{})
+	// Some node versions match the subscription query more broadly, so only
+	// record earnings of actual challenge txs
+	if !hasEvent(events, "message.action", challengeAction) {
+		br.Log.Debug("skipping message without the challenge action", "action", challengeAction, "actions", events["message.action"])
+		return
+	}
+
+	// Skip txs we have already processed (e.g. when backfill is re-run)
+	txHash := firstEvent(events, "tx.hash")
+	if txHash != "" {
+		var count int64
+		if err := br.DB.Model(&models.ProcessedTx{}).Where("hash = ?", txHash).Count(&count).Error; err != nil {
+			logger.Printf("Error checking processed tx %s: %v", txHash, err)
+			return
+		}
+		if count > 0 {
+			log.Printf("Skipping already processed tx %s", txHash)
+			return
+		}
+	}
+
+	// 2) Parse the client_data entries and resolve their solana addresses
+	records, problems := extractClients(events, br.SolanaAddressKeys)
+	for _, problem := range problems {
+		logger.Printf("Warning: %v", problem)
+	}
+	if len(records) < len(clientDataList) {
+		metrics.MalformedMessages.WithLabelValues("invalid_client_data").Inc()
+	}
 
 	log.Println("Client Data list:", clientDataList)
 
-	// Fetch the current epoch from Soarchain (only once per message).
-	// You could also fetch it once per block or on a timer, depending on performance needs.
-	epochInfo, err := getCurrentEpoch()
+	if br.DryRun {
+		br.logDryRun(txHash, records, epochs, timestamp, logger)
+		return
+	}
+
+	// All clients of a message are stored in one transaction together with
+	// the processed tx marker, so a message's effects are committed at once.
+	// Each client gets its own savepoint, so one bad entry is skipped without
+	// discarding the others. New clients are only announced once committed.
+	var newClients []NewClientEvent
+	err := br.transaction(logger, "tx "+txHash, func(tx *gorm.DB) error {
+		newClients = newClients[:0]
+		for _, record := range records {
+			data := record.Data
+			if record.SolanaSource != "" {
+				br.Log.Debug("resolved solana address", "client", data.Address, "source", record.SolanaSource)
+			}
+
+			// Parse the earnings
+			earningsValue, err := br.parseEarnings(data.Earnings)
+			if err != nil {
+				logger.Printf("Skipping earnings of client %s: %v", data.Address, err)
+				continue
+			}
+
+			var created bool
+			err = tx.Transaction(func(tx *gorm.DB) error {
+				created, err = storeClientEarnings(tx, data, record.SolanaAddress, earningsValue, epochs, timestamp, br.StatusDownAfter)
+				return err
+			})
+			if err != nil {
+				// The whole message is retried; Postgres won't carry on
+				// with the transaction a conflict was found in
+				if isRetryable(err) {
+					return err
+				}
+				logger.Printf("Error storing earnings of client %s: %v", data.Address, err)
+				continue
+			}
+			if created {
+				newClients = append(newClients, newClientEvent(data, record.SolanaAddress, timestamp))
+			}
+			log.Printf("Stored client info: Address=%s, PubKey=%s, SolanaAddr=%s, Earned=%d\n",
+				data.Address, data.PubKey, record.SolanaAddress, earningsValue)
+		}
+
+		if txHash == "" {
+			return nil
+		}
+		processed := models.ProcessedTx{
+			Hash:   txHash,
+			Height: parseHeight(firstEvent(events, "tx.height")),
+		}
+		// The live reader and a backfill may store the same tx at once;
+		// whichever commits second rolls back instead of counting it twice
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&processed)
+		if result.Error == nil && result.RowsAffected == 0 {
+			return errTxProcessed
+		}
+		return result.Error
+	})
+	if errors.Is(err, errTxProcessed) {
+		logger.Printf("Skipping tx %s, stored concurrently", txHash)
+		return
+	}
 	if err != nil {
-		logger.Printf("Error fetching epoch info: %v", err)
-		// Optional: continue or skip
+		logger.Printf("Error storing message (tx %s): %v", txHash, err)
 		return
 	}
+	for _, event := range newClients {
+		br.announceNewClient(event)
+	}
+}
 
-	for i, clientDataRaw := range clientDataList {
-		clientDataJSON, ok := clientDataRaw.(string)
-		if !ok {
+// clientData is one entry of a runner_challenge message.client_data event
+type clientData struct {
+	Address       string `json:"address"`
+	Earnings      coins  `json:"earnings"`
+	PubKey        string `json:"pubkey"`
+	SolanaAddress string `json:"solanaAddress"`
+}
+
+// clientRecord is a parsed client_data entry with its resolved solana address
+type clientRecord struct {
+	Data          clientData
+	SolanaAddress string // empty when no source has one
+	SolanaSource  string // the SolanaAddressKeys entry it was read from
+}
+
+// extractClients parses the message.client_data entries of a tx's events
+// and resolves each client's solana address from solanaAddressKeys. Entries
+// that can't be parsed are left out and reported in problems, along with
+// solana address lists that aren't parallel to client_data.
+func extractClients(events map[string][]string, solanaAddressKeys []string) (records []clientRecord, problems []error) {
+	clientDataList := events["message.client_data"]
+
+	// Event keys listing solana addresses are parallel to client_data
+	for _, key := range solanaAddressKeys {
+		if key == config.EmbeddedSolanaAddressKey {
 			continue
 		}
-
-		// Parse JSON for each client
-		var clientData struct {
-			Address       string `json:"address"`
-			Earnings      string `json:"earnings"`
-			PubKey        string `json:"pubkey"`
-			SolanaAddress string `json:"solanaAddress"`
+		if list := events[key]; len(list) > 0 && len(list) != len(clientDataList) {
+			problems = append(problems, fmt.Errorf("%d client_data entries but %d %s entries; falling back to other sources or core addresses",
+				len(clientDataList), len(list), key))
 		}
-		if err := json.Unmarshal([]byte(clientDataJSON), &clientData); err != nil {
-			logger.Printf("Error parsing client data: %v", err)
+	}
+
+	for i, clientDataJSON := range clientDataList {
+		var data clientData
+		if err := json.Unmarshal([]byte(clientDataJSON), &data); err != nil {
+			problems = append(problems, fmt.Errorf("parsing client data: %w", err))
 			continue
 		}
+		solanaAddress, source := resolveSolanaAddress(events, solanaAddressKeys, data, i, len(clientDataList))
+		records = append(records, clientRecord{
+			Data:          data,
+			SolanaAddress: solanaAddress,
+			SolanaSource:  source,
+		})
+	}
+	return records, problems
+}
 
-		// If we expect parallel arrays for solana_address
-		var solanaAddress string
-		if len(solanaAddressList) > i {
-			solanaAddress, _ = solanaAddressList[i].(string)
+// resolveSolanaAddress returns the solana address of the i-th of count
+// clients from the first of keys that has one, along with that key. Event
+// lists are only used when they are parallel to client_data. It returns
+// empty strings when no source has an address.
+func resolveSolanaAddress(events map[string][]string, keys []string, data clientData, i, count int) (string, string) {
+	for _, key := range keys {
+		if key == config.EmbeddedSolanaAddressKey {
+			if data.SolanaAddress != "" {
+				return data.SolanaAddress, key
+			}
+			continue
 		}
-		// If the chain is embedding the solana address in clientData
-		if clientData.SolanaAddress != "" {
-			solanaAddress = clientData.SolanaAddress
+		if list := events[key]; len(list) == count && list[i] != "" {
+			return list[i], key
 		}
+	}
+	return "", ""
+}
 
-		// Parse the earnings
-		earningsValue := parseEarnings(clientData.Earnings)
-		timestamp := time.Now().UTC()
+// storeClientEarnings upserts the client and records its earnings row,
+// epoch totals and any status transitions within tx. A client coming back
+// after more than downAfter without a challenge is recorded as having gone
+// Down and Up again. It reports whether the client was seen for the first
+// time.
+func storeClientEarnings(
+	tx *gorm.DB,
+	data clientData,
+	solanaAddress string,
+	earningsValue int64,
+	epochs []EpochInfo,
+	timestamp time.Time,
+	downAfter time.Duration,
+) (bool, error) {
+	// Earnings are keyed by solana address; without one, fall back to the
+	// core address so the rows stay attributable to the client
+	earningsAddress := solanaAddress
+	if earningsAddress == "" {
+		earningsAddress = data.Address
+	}
 
-		// Upsert logic
-		tx := br.DB.Begin()
-		var client models.Client
+	// Unscoped so a soft-deleted (inactive) client is revived, not re-created.
+	// The row is locked so concurrent messages for the same client don't
+	// overwrite each other's lifetime total.
+	var client models.Client
+	lookup := func() error {
+		client = models.Client{}
+		return tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&client, "address = ?", data.Address).Error
+	}
 
-		result := tx.First(&client, "address = ?", clientData.Address)
+	created := false
+	err := lookup()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// If record not found, create new. A concurrent message may have
+		// created it since the lookup; the insert then does nothing and the
+		// client is updated below instead of failing on the primary key,
+		// which would roll back this message's earnings.
+		client = models.Client{
+			Address:               data.Address,
+			PubKey:                data.PubKey,
+			SolanaAddress:         solanaAddress,
+			TotalLifetimeEarnings: earningsValue,
+			LastChallengeTime:     timestamp,
+			CreatedAt:             timestamp,
+			ChallengeCount:        1,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&client)
 		if result.Error != nil {
-			// If record not found, create new
-			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				client = models.Client{
-					Address:               clientData.Address,
-					PubKey:                clientData.PubKey,
-					SolanaAddress:         solanaAddress,
-					TotalLifetimeEarnings: earningsValue,
-					LastChallengeTime:     timestamp,
-				}
-				if err := tx.Create(&client).Error; err != nil {
-					tx.Rollback()
-					logger.Printf("Error inserting client: %v", err)
-					continue
-				}
-			} else {
-				// Some DB error
-				tx.Rollback()
-				logger.Printf("Error querying client: %v", result.Error)
-				continue
+			return false, fmt.Errorf("inserting client: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			created = true
+			if err := recordTransition(tx, data.Address, types.StatusUp, timestamp); err != nil {
+				return false, err
 			}
 		} else {
-			// If found, update existing
-			client.TotalLifetimeEarnings += earningsValue
-			if solanaAddress != "" {
-				client.SolanaAddress = solanaAddress
-			}
-			client.LastChallengeTime = timestamp
-			if err := tx.Save(&client).Error; err != nil {
-				tx.Rollback()
-				logger.Printf("Error updating client: %v", err)
-				continue
-			}
+			err = lookup()
 		}
+	}
 
-		// Insert a new ClientEarning row
-		clientEarning := models.ClientEarning{
-			ClientAddress: clientData.SolanaAddress,
-			Earnings:      earningsValue,
-			Timestamp:     timestamp,
+	if !created {
+		if err != nil {
+			return false, fmt.Errorf("querying client: %w", err)
 		}
-		if err := tx.Create(&clientEarning).Error; err != nil {
-			tx.Rollback()
-			logger.Printf("Error inserting client earnings: %v", err)
-			continue
+		// If found, update existing
+		client.TotalLifetimeEarnings += earningsValue
+		client.ChallengeCount++
+		if client.CreatedAt.IsZero() || timestamp.Before(client.CreatedAt) {
+			client.CreatedAt = timestamp
+		}
+		if solanaAddress != "" {
+			// Earnings recorded before the solana address was known are
+			// keyed by the core address; move them to the solana address
+			if client.SolanaAddress == "" {
+				if err := ReattributeEarnings(tx, data.Address, solanaAddress); err != nil {
+					return false, fmt.Errorf("reattributing earnings: %w", err)
+				}
+			}
+			client.SolanaAddress = solanaAddress
+		}
+		// Backfilled earnings may be older than what we've already seen
+		if timestamp.After(client.LastChallengeTime) {
+			if client.LastChallengeTime.IsZero() {
+				if err := recordTransition(tx, data.Address, types.StatusUp, timestamp); err != nil {
+					return false, err
+				}
+			} else if downAt := client.LastChallengeTime.Add(downAfter); !timestamp.Before(downAt) {
+				if err := recordTransition(tx, data.Address, types.StatusDown, downAt); err != nil {
+					return false, err
+				}
+				if err := recordTransition(tx, data.Address, types.StatusUp, timestamp); err != nil {
+					return false, err
+				}
+			}
+			client.LastChallengeTime = timestamp
+			client.DeletedAt = gorm.DeletedAt{}
 		}
+		if err := tx.Unscoped().Save(&client).Error; err != nil {
+			return false, fmt.Errorf("updating client: %w", err)
+		}
+	}
 
-		// ------------------------------------------------------------------------
-		// Upsert into epoch_earnings
-		// ------------------------------------------------------------------------
-		if err := upsertEpochEarnings(tx, clientData.SolanaAddress, earningsValue, epochInfo); err != nil {
-			tx.Rollback()
-			logger.Printf("Error upserting epoch earnings: %v", err)
-			continue
+	// Insert a new ClientEarning row
+	clientEarning := models.ClientEarning{
+		ClientAddress: earningsAddress,
+		CoreAddress:   data.Address,
+		Earnings:      earningsValue,
+		Timestamp:     timestamp,
+	}
+	if err := tx.Create(&clientEarning).Error; err != nil {
+		return false, fmt.Errorf("inserting client earnings: %w", err)
+	}
+
+	// ------------------------------------------------------------------------
+	// Upsert into epoch_earnings
+	// ------------------------------------------------------------------------
+	for _, epochInfo := range epochs {
+		if err := upsertEpochEarnings(tx, earningsAddress, earningsValue, epochInfo); err != nil {
+			return false, fmt.Errorf("upserting epoch earnings: %w", err)
 		}
+	}
+	return created, nil
+}
+
+// recordTransition stores a status transition of the client at address.
+func recordTransition(tx *gorm.DB, address string, status types.MinerStatus, at time.Time) error {
+	transition := models.StatusTransition{
+		ClientAddress: address,
+		Status:        string(status),
+		Timestamp:     at,
+	}
+	if err := tx.Create(&transition).Error; err != nil {
+		return fmt.Errorf("inserting %s transition: %w", status, err)
+	}
+	return nil
+}
+
+// firstEvent returns the first value of an event attribute, or "".
+func firstEvent(events map[string][]string, key string) string {
+	if values := events[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
 
-		tx.Commit()
-		log.Printf("Stored client info: Address=%s, PubKey=%s, SolanaAddr=%s, Earned=%d\n",
-			clientData.Address, clientData.PubKey, solanaAddress, earningsValue)
+// hasEvent reports whether key has an attribute equal to value.
+func hasEvent(events map[string][]string, key, value string) bool {
+	for _, v := range events[key] {
+		if v == value {
+			return true
+		}
 	}
+	return false
 }
 
-// parseEarnings removes "usoar" suffix and converts to int64
-func parseEarnings(earningsStr string) int64 {
-	// If the chain is consistently "usoar", remove that suffix
-	earningsStr = strings.TrimSuffix(earningsStr, "usoar")
-	value, err := strconv.ParseInt(earningsStr, 10, 64)
+// parseHeight converts a block height attribute, returning 0 when absent.
+func parseHeight(height string) int64 {
+	value, err := strconv.ParseInt(height, 10, 64)
 	if err != nil {
 		return 0
 	}
 	return value
 }
 
-// upsertEpochEarnings aggregates into a new or existing epoch record
+// earningsPattern splits a coin string such as "1500usoar" into amount and
+// denom
+var earningsPattern = regexp.MustCompile(`^(\d+)([a-zA-Z][a-zA-Z0-9/:._-]*)?$`)
+
+// coins is the earnings of a client_data entry: a single coin string, as
+// sent today, or an array of them should a challenge reward several denoms.
+// A string may itself list several coins separated by commas, as the SDK
+// prints them.
+type coins []string
+
+func (c *coins) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*c = coins{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("earnings must be a coin string or an array of them: %w", err)
+	}
+	*c = list
+	return nil
+}
+
+// parseEarnings sums the amounts of the coins in ExpectedDenom, in int64
+// micro-units. A bare amount is taken to be in ExpectedDenom. Coins of other
+// denoms are skipped (and logged at debug), but earnings without any coin in
+// ExpectedDenom are an error, so a chain upgrade changing the denom doesn't
+// silently record nothing.
+func (br *BlockReader) parseEarnings(earnings coins) (int64, error) {
+	expectedDenom := br.ExpectedDenom
+	var (
+		total   int64
+		matched bool
+		skipped []string
+	)
+	for _, entry := range earnings {
+		for _, coin := range strings.Split(entry, ",") {
+			match := earningsPattern.FindStringSubmatch(strings.TrimSpace(coin))
+			if match == nil {
+				return 0, fmt.Errorf("invalid earnings %q", coin)
+			}
+			if denom := match[2]; denom != "" && denom != expectedDenom {
+				skipped = append(skipped, coin)
+				continue
+			}
+			value, err := strconv.ParseInt(match[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid earnings amount %q: %w", coin, err)
+			}
+			if total > math.MaxInt64-value {
+				return 0, fmt.Errorf("earnings %v overflow", []string(earnings))
+			}
+			total += value
+			matched = true
+		}
+	}
+	if !matched {
+		return 0, fmt.Errorf("no earnings in denom %q in %v", expectedDenom, []string(earnings))
+	}
+	if len(skipped) > 0 {
+		br.Log.Debug("ignoring earnings in other denoms", "coins", skipped, "denom", expectedDenom)
+	}
+	return total, nil
+}
+
+// upsertEpochEarnings aggregates into a new or existing epoch record and
+// counts the outcome in metrics.EpochUpserts.
 func upsertEpochEarnings(
 	tx *gorm.DB,
 	clientAddress string,
 	earningsValue int64,
 	epochInfo EpochInfo,
 ) error {
+	outcome, err := upsertEpochRecord(tx, clientAddress, earningsValue, epochInfo)
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.EpochUpserts.WithLabelValues(epochInfo.Identifier, outcome).Inc()
+	return err
+}
+
+// upsertEpochRecord does the work of upsertEpochEarnings, returning whether
+// the record was "created" or "updated".
+func upsertEpochRecord(
+	tx *gorm.DB,
+	clientAddress string,
+	earningsValue int64,
+	epochInfo EpochInfo,
+) (string, error) {
 
 	epochNumber := epochInfo.CurrentEpoch
-	startTime := epochInfo.CurrentEpochStart
-	endTime := startTime.Add(epochInfo.Duration) // e.g., start + 86400s
+	// Postgres keeps microseconds, so compare at that precision
+	startTime := epochInfo.CurrentEpochStart.Truncate(time.Microsecond)
+	endTime := epochInfo.End()
 
+	// The start time tells an epoch apart from an older one with the same
+	// number, left over from before a chain reset or network switch
 	var epochRecord models.EpochEarnings
-	err := tx.Where("client_address = ? AND epoch_number = ?", clientAddress, epochNumber).
+	err := tx.Where("client_address = ? AND identifier = ? AND epoch_number = ? AND start_time = ?",
+		clientAddress, epochInfo.Identifier, epochNumber, startTime).
 		First(&epochRecord).Error
 
 	if err != nil {
@@ -344,6 +1094,7 @@ func upsertEpochEarnings(
 			// Insert new epoch record
 			epochRecord = models.EpochEarnings{
 				ClientAddress: clientAddress,
+				Identifier:    epochInfo.Identifier,
 				EpochNumber:   epochNumber,
 				StartTime:     startTime,
 				EndTime:       endTime,
@@ -352,19 +1103,18 @@ func upsertEpochEarnings(
 				UpdatedAt:     time.Now().UTC(),
 			}
 			if err := tx.Create(&epochRecord).Error; err != nil {
-				return err
+				return "", err
 			}
-		} else {
-			return err
-		}
-	} else {
-		// Record found, update
-		epochRecord.TotalEarnings += earningsValue
-		epochRecord.UpdatedAt = time.Now().UTC()
-		if err := tx.Save(&epochRecord).Error; err != nil {
-			return err
+			return "created", nil
 		}
+		return "", err
 	}
 
-	return nil
+	// Record found, update
+	epochRecord.TotalEarnings += earningsValue
+	epochRecord.UpdatedAt = time.Now().UTC()
+	if err := tx.Save(&epochRecord).Error; err != nil {
+		return "", err
+	}
+	return "updated", nil
 }
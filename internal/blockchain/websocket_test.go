@@ -0,0 +1,756 @@
+package blockchain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Soar-Robotics/SoarchainObserver/internal/metrics"
+	"github.com/Soar-Robotics/SoarchainObserver/internal/models"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeNode is a WebSocket RPC node that answers the subscribe request with
+// ack and then sends an empty event result every interval until the
+// connection is closed. It counts the subscriptions it has accepted.
+type fakeNode struct {
+	ack      string
+	interval time.Duration
+
+	mu            sync.Mutex
+	subscriptions int
+}
+
+const okSubscribeAck = `{"jsonrpc":"2.0","id":1,"result":{}}`
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if _, _, err := conn.ReadMessage(); err != nil {
+		return
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(n.ack)); err != nil {
+		return
+	}
+	n.mu.Lock()
+	n.subscriptions++
+	n.mu.Unlock()
+
+	if n.interval == 0 {
+		conn.ReadMessage() // hold the connection until the client closes it
+		return
+	}
+	for {
+		time.Sleep(n.interval)
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"result":{"events":{}}}`)); err != nil {
+			return
+		}
+	}
+}
+
+func (n *fakeNode) Subscriptions() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.subscriptions
+}
+
+// newFakeNodeReader starts node and returns a reader for it, not yet
+// connected.
+func newFakeNodeReader(t *testing.T, node *fakeNode) *BlockReader {
+	t.Helper()
+	srv := httptest.NewServer(node)
+	t.Cleanup(func() {
+		srv.CloseClientConnections()
+		srv.Close()
+	})
+	cfg := loadTestConfig(t, `{"rpc_endpoint": "`+wsURL(srv)+`"}`)
+	br, err := newBlockReader(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return br
+}
+
+// TestReconnectWhileReading replaces the connection while ReadBlocks reads
+// from it and the handlers poll its state; run with -race.
+func TestReconnectWhileReading(t *testing.T) {
+	node := &fakeNode{ack: okSubscribeAck, interval: time.Millisecond}
+	br := newFakeNodeReader(t, node)
+	if err := br.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		br.ReadBlocks(ctx, quietLogger)
+	}()
+
+	// The replaced connections stay open until the end: closing one the
+	// reader is blocked on would send it through the reconnection backoff
+	var replaced []*websocket.Conn
+	defer func() {
+		for _, conn := range replaced {
+			conn.Close()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			old := br.currentConn()
+			if err := br.Connect(); err != nil {
+				t.Errorf("reconnect %d: %v", i, err)
+				return
+			}
+			replaced = append(replaced, old)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			br.LastMessageAt()
+			br.ReconnectStats()
+			br.currentConn()
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for br.LastMessageAt().IsZero() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if br.LastMessageAt().IsZero() {
+		t.Error("no message was read")
+	}
+	if got := node.Subscriptions(); got != 11 {
+		t.Errorf("node accepted %d subscriptions, want 11", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadBlocks didn't return after cancel")
+	}
+}
+
+func TestExtractClients(t *testing.T) {
+	keys := []string{"client_data", "solana_address", "message.solana_address"}
+	const (
+		alice = `{"address":"soar1alice","pubkey":"pka","earnings":"100usoar","solanaAddress":"SoLalice"}`
+		bob   = `{"address":"soar1bob","pubkey":"pkb","earnings":"200usoar"}`
+		carol = `{"address":"soar1carol","pubkey":"pkc","earnings":["300usoar"]}`
+	)
+
+	type want struct {
+		address, solana, source string
+	}
+	tests := []struct {
+		name     string
+		events   map[string][]string
+		keys     []string
+		want     []want
+		problems []string // substrings, one per expected problem
+	}{
+		{
+			name:   "no client data",
+			events: map[string][]string{"message.action": {"runner_challenge"}},
+			keys:   keys,
+		},
+		{
+			name:   "embedded address",
+			events: map[string][]string{"message.client_data": {alice}},
+			keys:   keys,
+			want:   []want{{"soar1alice", "SoLalice", "client_data"}},
+		},
+		{
+			name: "parallel event list",
+			events: map[string][]string{
+				"message.client_data": {bob, carol},
+				"solana_address":      {"SoLbob", "SoLcarol"},
+			},
+			keys: keys,
+			want: []want{{"soar1bob", "SoLbob", "solana_address"}, {"soar1carol", "SoLcarol", "solana_address"}},
+		},
+		{
+			name: "embedded address preferred over the event list",
+			events: map[string][]string{
+				"message.client_data": {alice},
+				"solana_address":      {"SoLother"},
+			},
+			keys: keys,
+			want: []want{{"soar1alice", "SoLalice", "client_data"}},
+		},
+		{
+			name: "key order decides",
+			events: map[string][]string{
+				"message.client_data": {alice},
+				"solana_address":      {"SoLother"},
+			},
+			keys: []string{"solana_address", "client_data"},
+			want: []want{{"soar1alice", "SoLother", "solana_address"}},
+		},
+		{
+			name: "empty entry falls through to the next key",
+			events: map[string][]string{
+				"message.client_data":    {bob, carol},
+				"solana_address":         {"", "SoLcarol"},
+				"message.solana_address": {"SoLbob", ""},
+			},
+			keys: keys,
+			want: []want{{"soar1bob", "SoLbob", "message.solana_address"}, {"soar1carol", "SoLcarol", "solana_address"}},
+		},
+		{
+			name: "mismatched list is ignored",
+			events: map[string][]string{
+				"message.client_data": {alice, bob},
+				"solana_address":      {"SoLbob"},
+			},
+			keys:     keys,
+			want:     []want{{"soar1alice", "SoLalice", "client_data"}, {"soar1bob", "", ""}},
+			problems: []string{"2 client_data entries but 1 solana_address entries"},
+		},
+		{
+			name: "mismatched list falls back to a parallel one",
+			events: map[string][]string{
+				"message.client_data":    {bob},
+				"solana_address":         {"SoLx", "SoLy"},
+				"message.solana_address": {"SoLbob"},
+			},
+			keys:     keys,
+			want:     []want{{"soar1bob", "SoLbob", "message.solana_address"}},
+			problems: []string{"1 client_data entries but 2 solana_address entries"},
+		},
+		{
+			name: "address list without client data",
+			events: map[string][]string{
+				"solana_address": {"SoLorphan"},
+			},
+			keys:     keys,
+			problems: []string{"0 client_data entries but 1 solana_address entries"},
+		},
+		{
+			name: "malformed entries are skipped",
+			events: map[string][]string{
+				"message.client_data": {`{"address":`, bob, `{"address":"soar1x","earnings":42}`, `[]`},
+				"solana_address":      {"SoL0", "SoLbob", "SoL2", "SoL3"},
+			},
+			keys:     keys,
+			want:     []want{{"soar1bob", "SoLbob", "solana_address"}},
+			problems: []string{"parsing client data", "earnings must be a coin string", "parsing client data"},
+		},
+		{
+			name: "no address anywhere",
+			events: map[string][]string{
+				"message.client_data": {bob},
+			},
+			keys: keys,
+			want: []want{{"soar1bob", "", ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, problems := extractClients(tt.events, tt.keys)
+
+			var got []want
+			for _, record := range records {
+				got = append(got, want{record.Data.Address, record.SolanaAddress, record.SolanaSource})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("records = %+v, want %+v", got, tt.want)
+			}
+
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("problems = %v, want %d", problems, len(tt.problems))
+			}
+			for i, problem := range problems {
+				if !strings.Contains(problem.Error(), tt.problems[i]) {
+					t.Errorf("problem %d = %q, want it to mention %q", i, problem, tt.problems[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	for _, tt := range []struct {
+		logLevel  string
+		wantDebug bool
+	}{
+		{"", false},
+		{"info", false},
+		{"debug", true},
+	} {
+		cfg := loadTestConfig(t, `{"log_level": "`+tt.logLevel+`"}`)
+		br, err := newBlockReader(cfg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := br.Log.Enabled(context.Background(), slog.LevelDebug); got != tt.wantDebug {
+			t.Errorf("log_level %q: debug enabled = %v, want %v", tt.logLevel, got, tt.wantDebug)
+		}
+	}
+}
+
+// captureLog points br.Log at a buffer logging every level
+func captureLog(br *BlockReader) *bytes.Buffer {
+	var buf bytes.Buffer
+	br.Log = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return &buf
+}
+
+func TestProcessEventsLogsSolanaSource(t *testing.T) {
+	cfg := loadTestConfig(t, `{"log_level": "debug"}`)
+	br, err := newBlockReader(cfg, openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logged := captureLog(br)
+
+	br.processEvents(map[string][]string{
+		"tx.hash":             {"TX1"},
+		"message.action":      {challengeAction},
+		"message.client_data": {`{"address":"soar1alice","pubkey":"pk","earnings":"100usoar"}`},
+		"solana_address":      {"SoLalice"},
+	}, nil, time.Now(), quietLogger)
+
+	if !strings.Contains(logged.String(), `"msg":"resolved solana address","client":"soar1alice","source":"solana_address"`) {
+		t.Errorf("log %q doesn't report the solana address source", logged)
+	}
+}
+
+func TestProcessEventsSkipsOtherActions(t *testing.T) {
+	db := openTestDB(t)
+	br, err := newBlockReader(loadTestConfig(t, `{}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logged := captureLog(br)
+
+	br.processEvents(map[string][]string{
+		"tx.hash":             {"TX1"},
+		"message.action":      {"/cosmos.bank.v1beta1.MsgSend"},
+		"message.client_data": {`{"address":"soar1alice","pubkey":"pk","earnings":"100usoar"}`},
+	}, nil, time.Now(), quietLogger)
+
+	var stored int64
+	if err := db.Table("client_earnings").Count(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored != 0 {
+		t.Errorf("stored %d earnings rows of a tx without the challenge action", stored)
+	}
+	if !strings.Contains(logged.String(), `"level":"DEBUG","msg":"skipping message without the challenge action"`) {
+		t.Errorf("log %q doesn't report the skipped message at debug", logged)
+	}
+}
+
+// TestShutdownDuringOutage cancels ReadBlocks while the node is down and
+// the reader waits to reconnect.
+func TestShutdownDuringOutage(t *testing.T) {
+	node := &fakeNode{ack: okSubscribeAck}
+	srv := httptest.NewServer(node)
+	defer srv.Close()
+	cfg := loadTestConfig(t, `{"rpc_endpoint": "`+wsURL(srv)+`"}`)
+	br, err := newBlockReader(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := br.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		br.ReadBlocks(ctx, logger)
+	}()
+
+	// The node goes away: the connection drops and redialing would fail
+	srv.Close()
+	br.closeConn()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ReadBlocks kept reconnecting after the context was cancelled")
+	}
+	if !strings.Contains(logs.String(), "Attempting to reconnect") ||
+		!strings.Contains(logs.String(), "reconnection aborted due to shutdown") {
+		t.Errorf("log does not show the aborted reconnection:\n%s", logs.String())
+	}
+}
+
+func TestHandleReconnectionReturnsErrShutdown(t *testing.T) {
+	br := &BlockReader{URL: "ws://127.0.0.1:1/websocket", Dialer: &websocket.Dialer{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := br.handleReconnection(ctx, quietLogger); !errors.Is(err, ErrShutdown) {
+		t.Errorf("handleReconnection = %v, want ErrShutdown", err)
+	}
+}
+
+func TestParseEpochDuration(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{raw: "86400s", want: 24 * time.Hour},
+		{raw: "24h", want: 24 * time.Hour},
+		{raw: "1440m", want: 24 * time.Hour},
+		{raw: "86400", want: 24 * time.Hour},
+		{raw: " 3600s ", want: time.Hour},
+		{raw: "1.5", want: 1500 * time.Millisecond},
+		{raw: "one day", wantErr: true},
+		{raw: "", wantErr: true},
+		{raw: "0s", wantErr: true},
+		{raw: "-60s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseEpochDuration(tt.raw)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseEpochDuration(%q) = %s, %v; want %s, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestGetCurrentEpochDurations(t *testing.T) {
+	start := time.Date(2025, 1, 16, 9, 4, 54, 0, time.UTC)
+	for _, tt := range []struct {
+		duration string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{duration: "86400s", want: 24 * time.Hour},
+		{duration: "24h", want: 24 * time.Hour},
+		{duration: "a day", wantErr: true},
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"epoch":{"identifier":"day","duration":%q,"current_epoch":"33","current_epoch_start_time":%q}}`,
+				tt.duration, start.Format(time.RFC3339Nano))
+		}))
+		info, err := getCurrentEpoch(srv.Client(), srv.URL+"/epochs", "day")
+		srv.Close()
+
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "failed to parse epoch duration") {
+				t.Errorf("duration %q: error %v, want a duration parse error", tt.duration, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("duration %q: %v", tt.duration, err)
+		}
+		if info.Duration != tt.want || !info.End().Equal(start.Add(tt.want)) {
+			t.Errorf("duration %q: got %s ending %s, want %s", tt.duration, info.Duration, info.End(), tt.want)
+		}
+	}
+}
+
+// TestProcessEventsWithShortSolanaList stores a message whose solana_address
+// list is shorter than client_data.
+func TestProcessEventsWithShortSolanaList(t *testing.T) {
+	db := openTestDB(t)
+	br, err := newBlockReader(loadTestConfig(t, `{}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+
+	br.processEvents(map[string][]string{
+		"tx.hash":        {"TX1"},
+		"message.action": {challengeAction},
+		"message.client_data": {
+			`{"address":"soar1alice","pubkey":"pka","earnings":"100usoar","solanaAddress":"SoLalice"}`,
+			`{"address":"soar1bob","pubkey":"pkb","earnings":"200usoar"}`,
+		},
+		"solana_address": {"SoLbob"},
+	}, nil, time.Now(), log.New(&logs, "", 0))
+
+	if !strings.Contains(logs.String(), "Warning: 2 client_data entries but 1 solana_address entries") {
+		t.Errorf("log %q doesn't warn about the mismatched lists", logs.String())
+	}
+
+	var rows []models.ClientEarning
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, row := range rows {
+		got[row.CoreAddress] = row.ClientAddress
+	}
+	// The misaligned list isn't trusted: alice keeps her embedded address
+	// and bob's earnings fall back to his core address
+	want := map[string]string{"soar1alice": "SoLalice", "soar1bob": "soar1bob"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("earnings attributed to %v, want %v", got, want)
+	}
+}
+
+// TestProcessEventsStoresMessageAtomically stores a multi-client message in
+// one transaction, skipping the one client that fails to store.
+func TestProcessEventsStoresMessageAtomically(t *testing.T) {
+	db := openTestDB(t)
+	br, err := newBlockReader(loadTestConfig(t, `{}`), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	message := func(hash string, clients ...string) map[string][]string {
+		events := map[string][]string{"tx.hash": {hash}, "tx.height": {"7"}, "message.action": {challengeAction}}
+		for _, client := range clients {
+			events["message.client_data"] = append(events["message.client_data"],
+				`{"address":"`+client+`","pubkey":"pk","earnings":"100usoar","solanaAddress":"SoL`+client+`"}`)
+		}
+		return events
+	}
+	count := func(model interface{}, query string, args ...interface{}) int64 {
+		var n int64
+		if err := db.Model(model).Where(query, args...).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	br.processEvents(message("TX1", "soar1alice", "soar1bob", "soar1carol"), nil, now, quietLogger)
+	if n := count(&models.Client{}, "1 = 1"); n != 3 {
+		t.Errorf("stored %d clients, want 3", n)
+	}
+	if n := count(&models.ClientEarning{}, "1 = 1"); n != 3 {
+		t.Errorf("stored %d earnings rows, want 3", n)
+	}
+	if n := count(&models.ProcessedTx{}, "hash = ? AND height = 7", "TX1"); n != 1 {
+		t.Errorf("stored %d processed markers for TX1, want 1", n)
+	}
+
+	// New clients can't be stored without the transitions table, while an
+	// already known one still can: its savepoint alone is rolled back
+	if err := db.Migrator().DropTable(&models.StatusTransition{}); err != nil {
+		t.Fatal(err)
+	}
+	br.processEvents(message("TX2", "soar1dave", "soar1alice"), nil, now.Add(time.Minute), quietLogger)
+	if n := count(&models.Client{}, "address = ?", "soar1dave"); n != 0 {
+		t.Errorf("stored the client that failed")
+	}
+	if n := count(&models.ClientEarning{}, "core_address = ?", "soar1alice"); n != 2 {
+		t.Errorf("soar1alice has %d earnings rows, want 2", n)
+	}
+	if n := count(&models.ProcessedTx{}, "hash = ?", "TX2"); n != 1 {
+		t.Errorf("TX2 was not marked processed")
+	}
+}
+
+func TestConnectChecksSubscribeAck(t *testing.T) {
+	tests := []struct {
+		name    string
+		ack     string
+		wantErr string
+	}{
+		{"success", okSubscribeAck, ""},
+		{"error ack", `{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error","data":"max_subscriptions_per_client 5 reached"}}`,
+			"subscription error -32603: Internal error max_subscriptions_per_client 5 reached"},
+		{"other request id", `{"jsonrpc":"2.0","id":2,"result":{}}`, "unexpected subscription ack id 2"},
+		{"no result", `{"jsonrpc":"2.0","id":1}`, "subscription ack has no result"},
+		{"not json", `subscribed`, "invalid subscription ack"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			br := newFakeNodeReader(t, &fakeNode{ack: tt.ack})
+			err := br.Connect()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Connect: %v", err)
+				}
+				if br.currentConn() == nil {
+					t.Error("Connect succeeded without a connection")
+				}
+				br.closeConn()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Connect error = %v, want %q", err, tt.wantErr)
+			}
+			if br.currentConn() != nil {
+				t.Error("a rejected subscription left a connection behind")
+			}
+		})
+	}
+}
+
+func TestGetCurrentEpochEndTime(t *testing.T) {
+	start := time.Date(2025, 1, 16, 9, 4, 54, 532413149, time.UTC)
+	drifted := start.Add(24*time.Hour + 3*time.Second) // the chain's end, a little later than start + duration
+	tests := []struct {
+		name    string
+		end     string // current_epoch_end_time, omitted when empty
+		want    time.Time
+		wantErr string
+	}{
+		{name: "reported end", end: drifted.Format(time.RFC3339Nano), want: drifted},
+		{name: "no end reported", want: start.Add(24 * time.Hour)},
+		{name: "invalid end", end: "tomorrow", wantErr: "failed to parse current_epoch_end_time"},
+		{name: "end before start", end: start.Add(-time.Second).Format(time.RFC3339Nano), wantErr: "is not after current_epoch_start_time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end := ""
+			if tt.end != "" {
+				end = fmt.Sprintf(`,"current_epoch_end_time":%q`, tt.end)
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"epoch":{"identifier":"day","duration":"86400s","current_epoch":"33","current_epoch_start_time":%q%s}}`,
+					start.Format(time.RFC3339Nano), end)
+			}))
+			defer srv.Close()
+
+			info, err := getCurrentEpoch(srv.Client(), srv.URL+"/epochs", "day")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.End().Equal(tt.want) {
+				t.Errorf("End() = %s, want %s", info.End(), tt.want)
+			}
+
+			// The stored epoch row ends where the epoch info does
+			db := openTestDB(t)
+			if err := upsertEpochEarnings(db, "SoLalice", 100, info); err != nil {
+				t.Fatal(err)
+			}
+			var row models.EpochEarnings
+			if err := db.First(&row).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !row.EndTime.Equal(tt.want) {
+				t.Errorf("stored end %s, want %s", row.EndTime, tt.want)
+			}
+		})
+	}
+}
+
+// TestOversizedMessageDropsTheConnection feeds the reader a frame above its
+// read limit.
+func TestOversizedMessageDropsTheConnection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(okSubscribeAck))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"padding":"`+strings.Repeat("x", 4096)+`"}`))
+		conn.ReadMessage() // hold the connection until the client closes it
+	}))
+	defer srv.Close()
+
+	cfg := loadTestConfig(t, `{"rpc_endpoint": "`+wsURL(srv)+`", "max_message_bytes": 1024}`)
+	br, err := newBlockReader(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := br.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	oversized := metrics.MalformedMessages.WithLabelValues("oversized")
+	before := testutil.ToFloat64(oversized)
+
+	var logs syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		br.ReadBlocks(ctx, log.New(&logs, "", 0))
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), "Attempting to reconnect") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if !strings.Contains(logs.String(), "Message exceeds 1024 bytes, dropping the connection") {
+		t.Errorf("log %q doesn't report the oversized message", logs.String())
+	}
+	if got := testutil.ToFloat64(oversized) - before; got != 1 {
+		t.Errorf("oversized messages counter went up by %v, want 1", got)
+	}
+}
+
+func TestMalformedMessagesAreCounted(t *testing.T) {
+	br, err := newBlockReader(loadTestConfig(t, `{}`), openTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalidJSON := metrics.MalformedMessages.WithLabelValues("invalid_json")
+	before := testutil.ToFloat64(invalidJSON)
+
+	br.processMessage([]byte(`{"jsonrpc":"2.0",`), quietLogger)
+	if got := testutil.ToFloat64(invalidJSON) - before; got != 1 {
+		t.Errorf("invalid JSON counter went up by %v, want 1", got)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for a logger writing from another
+// goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestMonitorIdleClampsItsInterval runs the idle check with a duration too
+// short for a ticker interval of half of it.
+func TestMonitorIdleClampsItsInterval(t *testing.T) {
+	br := &BlockReader{MaxIdleDuration: time.Nanosecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*minIdleCheckInterval)
+	defer cancel()
+	br.monitorIdle(ctx, quietLogger) // panics on a non-positive interval
+}
@@ -2,12 +2,503 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"time"
 )
 
+// epochIdentifierPattern matches epoch identifiers such as "day" or "week"
+var epochIdentifierPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ValidEpochIdentifier reports whether identifier is usable as an epoch
+// identifier, e.g. as a URL path segment.
+func ValidEpochIdentifier(identifier string) bool {
+	return epochIdentifierPattern.MatchString(identifier)
+}
+
+// defaultExpectedChallengeInterval matches the runner-challenge cadence the
+// status thresholds were tuned for.
+const defaultExpectedChallengeInterval = time.Minute
+
+// defaultEpochStaleAfter is how long epoch fetches may fail before
+// readiness reports the epoch info as stale.
+const defaultEpochStaleAfter = 15 * time.Minute
+
+// defaultSlowQueryThreshold is the duration above which queries are logged
+const defaultSlowQueryThreshold = 200 * time.Millisecond
+
+// defaultStatusCacheTTL and defaultStatusCacheSize bound the miner status
+// cache
+const (
+	defaultStatusCacheTTL  = 5 * time.Second
+	defaultStatusCacheSize = 1024
+)
+
+// defaultStatusDegradedAfter and defaultStatusDownAfter bound the Degraded
+// band of the miner status
+const (
+	defaultStatusDegradedAfter = 2 * time.Minute
+	defaultStatusDownAfter     = 5 * time.Minute
+)
+
+// maxTokenDecimals keeps 10^TokenDecimals within an int64
+const maxTokenDecimals = 18
+
+// defaultMaxMessageBytes caps the size of a WebSocket message from the node
+const defaultMaxMessageBytes = 4 << 20
+
+// minMaxIdleDuration is the shortest max_idle_duration accepted; the idle
+// check runs every half of it
+const minMaxIdleDuration = time.Second
+
+// Defaults of the retries of earnings transactions failing on a
+// serialization failure or deadlock
+const (
+	defaultTxRetryAttempts = 3
+	defaultTxRetryBackoff  = 50 * time.Millisecond
+)
+
+// defaultWarmupTimeout bounds the startup backfill of WarmupEpochs
+const defaultWarmupTimeout = 10 * time.Minute
+
+// defaultShutdownTimeoutSeconds bounds the graceful shutdown
+const defaultShutdownTimeoutSeconds = 10
+
+// defaultHTTPTimeout bounds REST calls to the chain such as the epoch API
+const defaultHTTPTimeout = 5 * time.Second
+
+// EmbeddedSolanaAddressKey stands for the solanaAddress field embedded in
+// each client_data entry in SolanaAddressKeys
+const EmbeddedSolanaAddressKey = "client_data"
+
+// defaultSolanaAddressKeys prefers the embedded address, then the event
+// keys used by the node versions seen so far
+var defaultSolanaAddressKeys = []string{EmbeddedSolanaAddressKey, "solana_address", "message.solana_address"}
+
 type Config struct {
 	RPCEndpoint string `json:"rpc_endpoint"`
 	APIEndpoint string `json:"api_endpoint"`
+
+	// Network selects the Networks entry the chain-specific settings
+	// (token symbol, decimals, denom and epoch endpoint) default to.
+	// Defaults to "mainnet".
+	Network string `json:"network"`
+
+	// ChainID is the chain ID the RPC node must report at startup, so an
+	// RPC endpoint of the wrong network is caught before recording its
+	// data. The check is skipped while it is empty. A mismatch stops the
+	// observer unless AllowChainIDMismatch is set, which only logs it.
+	ChainID              string `json:"chain_id"`
+	AllowChainIDMismatch bool   `json:"allow_chain_id_mismatch"`
+
+	// EpochEndpoint is the base URL of the epoch API; the epoch identifier
+	// is appended as the last path segment. Defaults to the network's.
+	EpochEndpoint string `json:"epoch_endpoint"`
+
+	// MaxMessageBytes is the largest WebSocket message accepted from the
+	// node (default 4 MiB). A larger one drops the connection, which is then
+	// re-established.
+	MaxMessageBytes int64 `json:"max_message_bytes"`
+
+	// TxRetryAttempts is how many times a transaction storing earnings is
+	// attempted when Postgres aborts it with a serialization failure or
+	// deadlock (default 3), waiting TxRetryBackoff (default 50ms), doubled
+	// after every attempt, in between.
+	TxRetryAttempts int      `json:"tx_retry_attempts"`
+	TxRetryBackoff  Duration `json:"tx_retry_backoff"`
+
+	// MaxIdleDuration forces a WebSocket reconnect when no message has been
+	// received for this long. Zero (the default) disables the check, since a
+	// quiet chain can legitimately go a while without runner challenges;
+	// otherwise it must be at least 1s.
+	MaxIdleDuration Duration `json:"max_idle_duration"`
+
+	// TLSInsecureSkipVerify disables certificate verification for wss
+	// endpoints. Only meant for staging nodes with self-signed certs.
+	TLSInsecureSkipVerify bool `json:"tls_insecure_skip_verify"`
+	// TLSCACertPath optionally points to a PEM file with extra CA certs to
+	// trust for the RPC connection.
+	TLSCACertPath string `json:"tls_ca_cert_path"`
+
+	// ProxyURL overrides the HTTP_PROXY/HTTPS_PROXY environment variables for
+	// both the WebSocket connection and the epoch API requests.
+	ProxyURL string `json:"proxy_url"`
+
+	// RPCHeaders are sent with the WebSocket upgrade request, e.g. an API
+	// key required by a hosted node provider.
+	RPCHeaders map[string]string `json:"rpc_headers"`
+
+	// AdminAPIKey guards the /api/v1/admin endpoints. It can also be set via
+	// the ADMIN_API_KEY environment variable. Admin endpoints are disabled
+	// while it is empty.
+	AdminAPIKey string `json:"admin_api_key"`
+
+	// EnableProfiling mounts the net/http/pprof handlers under /debug/pprof,
+	// gated by the admin API key. Off by default.
+	EnableProfiling bool `json:"enable_profiling"`
+
+	// CORSAllowedOrigins restricts cross-origin requests to these origins,
+	// e.g. ["https://dashboard.soarchain.com"]. Any origin is allowed while
+	// it is empty.
+	CORSAllowedOrigins []string `json:"cors_allowed_origins"`
+
+	// TrustedProxies lists the IPs or CIDRs of the reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers give the client IP, e.g.
+	// ["10.0.0.0/8"]. While it is empty the headers are ignored and the
+	// client IP is the address of the connection.
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	// SecurityHeaders adds X-Content-Type-Options, X-Frame-Options and
+	// Referrer-Policy to every response.
+	SecurityHeaders bool `json:"security_headers"`
+
+	// AllowInvalidWallets accepts wallet params that aren't solana addresses
+	// on the status and reward endpoints, answering as for an unknown wallet
+	// instead of 400, for clients relying on the old lenient behaviour.
+	AllowInvalidWallets bool `json:"allow_invalid_wallets"`
+
+	// ExpectedChallengeInterval is how often a healthy miner is expected to
+	// be challenged. It depends on the chain's runner-challenge scheduling
+	// and is the single source for "how many challenges were expected in a
+	// window" (uptime, cadence gaps). Defaults to one minute.
+	ExpectedChallengeInterval Duration `json:"expected_challenge_interval"`
+
+	// EpochStaleAfter marks readiness as degraded once the epoch API has been
+	// failing and the last successful fetch is older than this.
+	EpochStaleAfter Duration `json:"epoch_stale_after"`
+
+	// RequestLogLevel is the level used to log successful HTTP requests
+	// ("debug", "info", ...). Client and server errors are always logged at
+	// warn and error. Defaults to "info".
+	RequestLogLevel string `json:"request_log_level"`
+
+	// LogLevel is the lowest level of the observer's structured log lines
+	// ("debug", "info", ...); "debug" adds the per-client ingestion details.
+	// Defaults to "info".
+	LogLevel string `json:"log_level"`
+
+	// SlowQueryThreshold logs every DB query taking longer than this, along
+	// with its SQL. Defaults to 200ms.
+	SlowQueryThreshold Duration `json:"slow_query_threshold"`
+
+	// HTTPTimeout is the overall timeout of REST calls to the chain (epoch
+	// API, backfill RPC). Defaults to 5s.
+	HTTPTimeout Duration `json:"http_timeout"`
+
+	// EpochIdentifiers lists the chain epochs earnings are aggregated into,
+	// e.g. ["day", "week"]. The first one is the primary epoch used where a
+	// single epoch is needed (backfill ranges, defaults). Defaults to ["day"].
+	EpochIdentifiers []string `json:"epoch_identifiers"`
+
+	// EnableEpochAggregation aggregates earnings into epoch_earnings, which
+	// costs an epoch API call per message. Deployments that only need raw
+	// earnings can set it to false, which also turns off the reward
+	// endpoints. Defaults to true.
+	EnableEpochAggregation *bool `json:"enable_epoch_aggregation"`
+
+	// EarningsAdjustments maps a wallet to the factor applied to its raw
+	// earnings by /timeframe-earnings, e.g. a payout share after fees.
+	// Wallets not listed are reported unadjusted.
+	EarningsAdjustments map[string]float64 `json:"earnings_adjustments"`
+
+	// InactiveClientAfter soft-deletes clients whose last challenge is older
+	// than this, hiding them from listings. Their earnings are kept and they
+	// reappear on their next challenge. Zero (the default) disables it.
+	InactiveClientAfter Duration `json:"inactive_client_after"`
+
+	// ArchiveEpochsAfterDays moves epochs that ended more than this many days
+	// ago from epoch_earnings into the compact epoch_archive table. The
+	// reward endpoints read both. Zero (the default) disables archiving.
+	ArchiveEpochsAfterDays int `json:"archive_epochs_after_days"`
+
+	// WarmupEpochs backfills this many recent epochs, the current one
+	// included, when the observer starts, holding /readyz at not ready until
+	// done or WarmupTimeout (default 10m) has passed. Zero (the default)
+	// skips it.
+	WarmupEpochs  int      `json:"warmup_epochs"`
+	WarmupTimeout Duration `json:"warmup_timeout"`
+
+	// ShutdownTimeoutSeconds bounds how long shutdown waits for in-flight
+	// HTTP requests and the ingestion and background workers (default 10).
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+
+	// NewClientWebhookURL, if set, receives a JSON POST whenever a client is
+	// seen for the first time. Disabled by default.
+	NewClientWebhookURL string `json:"new_client_webhook_url"`
+
+	// DryRun makes the observer parse and log the earnings it reads without
+	// writing them, for validating parser changes against live traffic. The
+	// inactive client sweep and the epoch archiver don't run either.
+	DryRun bool `json:"dry_run"`
+
+	// EpochWebhookURL, if set, receives a JSON POST with the totals of every
+	// epoch that ends while the observer runs. Disabled by default.
+	EpochWebhookURL string `json:"epoch_webhook_url"`
+
+	// PriceProviderURL, if set, is fetched whenever an epoch of the primary
+	// identifier ends, and must answer {"usd": <price>} with the USD price of
+	// the network's token. The price is stored as the price at the end of
+	// the epoch, to value past rewards. Disabled by default.
+	PriceProviderURL string `json:"price_provider_url"`
+
+	// StatusCacheTTL is how long /api/v1/miner/status reuses a wallet's
+	// lookup (default 5s); StatusCacheSize caps the number of cached wallets
+	// (default 1024), evicting the least recently used.
+	StatusCacheTTL  Duration `json:"status_cache_ttl"`
+	StatusCacheSize int      `json:"status_cache_size"`
+
+	// StatusDegradedAfter and StatusDownAfter are how long after its last
+	// challenge a miner stops being reported Up and starts being reported
+	// Down (defaults 2m and 5m); in between it is Degraded. Setting
+	// DisableDegradedStatus drops that band, keeping miners Up until
+	// StatusDownAfter.
+	StatusDegradedAfter   Duration `json:"status_degraded_after"`
+	StatusDownAfter       Duration `json:"status_down_after"`
+	DisableDegradedStatus bool     `json:"disable_degraded_status"`
+
+	// ExpectedDenom is the denom runner-challenge earnings are paid in.
+	// Earnings in any other denom are logged and skipped. Defaults to the
+	// network's.
+	ExpectedDenom string `json:"expected_denom"`
+
+	// SolanaAddressKeys lists, in order of preference, where a client's
+	// solana address is read from: event keys parallel to client_data such
+	// as "solana_address", or "client_data" for the address embedded in the
+	// client_data entry. The first non-empty one wins.
+	SolanaAddressKeys []string `json:"solana_address_keys"`
+
+	// TokenDecimals is the number of decimals between the earnings denom
+	// and a whole token (default the network's). Responses render token amounts with
+	// AmountDecimals places (default TokenDecimals), rounding half away from
+	// zero, and as JSON strings instead of numbers when AmountsAsStrings is
+	// set.
+	TokenDecimals    int  `json:"token_decimals"`
+	AmountDecimals   *int `json:"amount_decimals"`
+	AmountsAsStrings bool `json:"amounts_as_strings"`
+}
+
+// AdjustmentFactor returns the earnings adjustment configured for wallet,
+// or 1 if there is none.
+func (c *Config) AdjustmentFactor(wallet string) float64 {
+	if factor, ok := c.EarningsAdjustments[wallet]; ok {
+		return factor
+	}
+	return 1
+}
+
+// EpochAggregation reports whether earnings are aggregated into epochs.
+func (c *Config) EpochAggregation() bool {
+	return c.EnableEpochAggregation == nil || *c.EnableEpochAggregation
+}
+
+// TokenSymbol returns the token symbol of the configured network.
+func (c *Config) TokenSymbol() string {
+	return Networks[c.Network].Symbol
+}
+
+// DisplayDecimals returns the number of decimal places token amounts are
+// rendered with.
+func (c *Config) DisplayDecimals() int {
+	if c.AmountDecimals == nil {
+		return c.TokenDecimals
+	}
+	return *c.AmountDecimals
+}
+
+// ShutdownTimeout returns how long shutdown waits for the server and
+// workers to stop.
+func (c *Config) ShutdownTimeout() time.Duration {
+	return time.Duration(c.ShutdownTimeoutSeconds) * time.Second
+}
+
+// ExpectedChallenges returns how many challenges a fully available miner
+// should receive within window.
+func (c *Config) ExpectedChallenges(window time.Duration) int64 {
+	return int64(window / c.ExpectedChallengeInterval.Duration)
+}
+
+// applyDefaults fills in optional settings left out of config.json.
+func (c *Config) applyDefaults() {
+	if c.ExpectedChallengeInterval.Duration == 0 {
+		c.ExpectedChallengeInterval.Duration = defaultExpectedChallengeInterval
+	}
+	if c.EpochStaleAfter.Duration == 0 {
+		c.EpochStaleAfter.Duration = defaultEpochStaleAfter
+	}
+	if c.SlowQueryThreshold.Duration == 0 {
+		c.SlowQueryThreshold.Duration = defaultSlowQueryThreshold
+	}
+	if c.HTTPTimeout.Duration == 0 {
+		c.HTTPTimeout.Duration = defaultHTTPTimeout
+	}
+	if c.StatusCacheTTL.Duration == 0 {
+		c.StatusCacheTTL.Duration = defaultStatusCacheTTL
+	}
+	if c.StatusCacheSize == 0 {
+		c.StatusCacheSize = defaultStatusCacheSize
+	}
+	if c.StatusDegradedAfter.Duration == 0 {
+		c.StatusDegradedAfter.Duration = defaultStatusDegradedAfter
+	}
+	if c.StatusDownAfter.Duration == 0 {
+		c.StatusDownAfter.Duration = defaultStatusDownAfter
+	}
+	if len(c.EpochIdentifiers) == 0 {
+		c.EpochIdentifiers = []string{"day"}
+	}
+	if c.Network == "" {
+		c.Network = defaultNetwork
+	}
+	// An unknown network is reported by Validate
+	network := Networks[c.Network]
+	if c.EpochEndpoint == "" {
+		c.EpochEndpoint = network.EpochEndpoint
+	}
+	if c.ExpectedDenom == "" {
+		c.ExpectedDenom = network.Denom
+	}
+	if len(c.SolanaAddressKeys) == 0 {
+		c.SolanaAddressKeys = defaultSolanaAddressKeys
+	}
+	if c.WarmupTimeout.Duration == 0 {
+		c.WarmupTimeout.Duration = defaultWarmupTimeout
+	}
+	if c.MaxMessageBytes == 0 {
+		c.MaxMessageBytes = defaultMaxMessageBytes
+	}
+	if c.TxRetryAttempts == 0 {
+		c.TxRetryAttempts = defaultTxRetryAttempts
+	}
+	if c.TxRetryBackoff.Duration == 0 {
+		c.TxRetryBackoff.Duration = defaultTxRetryBackoff
+	}
+	if c.ShutdownTimeoutSeconds == 0 {
+		c.ShutdownTimeoutSeconds = defaultShutdownTimeoutSeconds
+	}
+	if c.TokenDecimals == 0 {
+		c.TokenDecimals = network.Decimals
+	}
+}
+
+// Validate reports settings that can't be used as given.
+func (c *Config) Validate() error {
+	if _, ok := Networks[c.Network]; !ok {
+		return fmt.Errorf("unknown network %q", c.Network)
+	}
+	if c.ExpectedChallengeInterval.Duration <= 0 {
+		return errors.New("expected_challenge_interval must be positive")
+	}
+	if c.EpochStaleAfter.Duration < 0 {
+		return errors.New("epoch_stale_after must not be negative")
+	}
+	if c.SlowQueryThreshold.Duration < 0 {
+		return errors.New("slow_query_threshold must not be negative")
+	}
+	if c.HTTPTimeout.Duration < 0 {
+		return errors.New("http_timeout must not be negative")
+	}
+	if c.StatusCacheTTL.Duration < 0 {
+		return errors.New("status_cache_ttl must not be negative")
+	}
+	if c.StatusCacheSize < 0 {
+		return errors.New("status_cache_size must not be negative")
+	}
+	if c.StatusDegradedAfter.Duration < 0 {
+		return errors.New("status_degraded_after must not be negative")
+	}
+	if c.StatusDownAfter.Duration < c.StatusDegradedAfter.Duration {
+		return errors.New("status_down_after must not be shorter than status_degraded_after")
+	}
+	if c.TokenDecimals < 0 || c.TokenDecimals > maxTokenDecimals {
+		return fmt.Errorf("token_decimals must be between 0 and %d", maxTokenDecimals)
+	}
+	if decimals := c.DisplayDecimals(); decimals < 0 || decimals > c.TokenDecimals {
+		return errors.New("amount_decimals must be between 0 and token_decimals")
+	}
+	seen := make(map[string]bool, len(c.EpochIdentifiers))
+	for _, identifier := range c.EpochIdentifiers {
+		if !ValidEpochIdentifier(identifier) {
+			return fmt.Errorf("invalid epoch identifier %q", identifier)
+		}
+		if seen[identifier] {
+			return fmt.Errorf("duplicate epoch identifier %q", identifier)
+		}
+		seen[identifier] = true
+	}
+	if endpoint, err := url.Parse(c.EpochEndpoint); err != nil ||
+		(endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("epoch_endpoint %q must be an http(s) URL", c.EpochEndpoint)
+	}
+	for name, webhook := range map[string]string{
+		"new_client_webhook_url": c.NewClientWebhookURL,
+		"epoch_webhook_url":      c.EpochWebhookURL,
+		"price_provider_url":     c.PriceProviderURL,
+	} {
+		if webhook == "" {
+			continue
+		}
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s %q must be an http(s) URL", name, webhook)
+		}
+	}
+	for wallet, factor := range c.EarningsAdjustments {
+		if factor < 0 {
+			return fmt.Errorf("earnings adjustment for %q must not be negative", wallet)
+		}
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			return errors.New("cors_allowed_origins must list origins; leave it empty to allow any")
+		}
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid CORS origin %q", origin)
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid trusted proxy %q: must be an IP or CIDR", proxy)
+		}
+	}
+	for _, key := range c.SolanaAddressKeys {
+		if key == "" {
+			return errors.New("solana_address_keys must not contain empty keys")
+		}
+	}
+	if c.InactiveClientAfter.Duration < 0 {
+		return errors.New("inactive_client_after must not be negative")
+	}
+	if c.WarmupEpochs < 0 {
+		return errors.New("warmup_epochs must not be negative")
+	}
+	if c.WarmupTimeout.Duration < 0 {
+		return errors.New("warmup_timeout must not be negative")
+	}
+	if c.MaxMessageBytes < 0 {
+		return errors.New("max_message_bytes must not be negative")
+	}
+	if c.TxRetryAttempts < 0 {
+		return errors.New("tx_retry_attempts must not be negative")
+	}
+	if c.TxRetryBackoff.Duration < 0 {
+		return errors.New("tx_retry_backoff must not be negative")
+	}
+	if c.ShutdownTimeoutSeconds < 0 {
+		return errors.New("shutdown_timeout_seconds must not be negative")
+	}
+	if c.ArchiveEpochsAfterDays < 0 {
+		return errors.New("archive_epochs_after_days must not be negative")
+	}
+	if c.MaxIdleDuration.Duration < 0 {
+		return errors.New("max_idle_duration must not be negative")
+	}
+	if c.MaxIdleDuration.Duration != 0 && c.MaxIdleDuration.Duration < minMaxIdleDuration {
+		return fmt.Errorf("max_idle_duration must be 0 (disabled) or at least %s", minMaxIdleDuration)
+	}
+	return nil
 }
 
 func LoadConfig(filePath string) (*Config, error) {
@@ -21,5 +512,10 @@ func LoadConfig(filePath string) (*Config, error) {
 		return nil, err
 	}
 
+	config.applyDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
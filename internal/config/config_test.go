@@ -0,0 +1,29 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaxIdleDurationBounds(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr string
+	}{
+		{`"0s"`, ""}, // disabled
+		{`"1s"`, ""},
+		{`"5m"`, ""},
+		{`"1ns"`, "at least 1s"},
+		{`"999ms"`, "at least 1s"},
+		{`"-1s"`, "must not be negative"},
+	}
+	for _, tt := range tests {
+		_, err := loadConfigJSON(t, `{"max_idle_duration": `+tt.value+`}`)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("max_idle_duration %s: %v", tt.value, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("max_idle_duration %s: error %v, want one containing %q", tt.value, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+// NetworkParams are the chain-specific constants of a network.
+type NetworkParams struct {
+	Symbol        string // token symbol reported in responses, e.g. "SOAR"
+	Decimals      int    // decimals between Denom and a whole token
+	Denom         string // denom earnings are paid in, e.g. "usoar"
+	EpochEndpoint string // base URL of the epoch API
+}
+
+// defaultNetwork is the network used when config.json doesn't name one
+const defaultNetwork = "mainnet"
+
+// Networks is the registry of known networks, keyed by the name given as
+// "network" in config.json.
+var Networks = map[string]NetworkParams{
+	"mainnet": {
+		Symbol:        "SOAR",
+		Decimals:      6,
+		Denom:         "usoar",
+		EpochEndpoint: "https://api.mainnet.soarchain.com/soarchain/epoch",
+	},
+	"testnet": {
+		Symbol:        "SOAR",
+		Decimals:      6,
+		Denom:         "usoar",
+		EpochEndpoint: "https://api.testnet.soarchain.com/soarchain/epoch",
+	},
+}
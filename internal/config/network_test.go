@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadConfigJSON loads configJSON through LoadConfig.
+func loadConfigJSON(t *testing.T, configJSON string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(configJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(path)
+}
+
+func TestNetworkRegistry(t *testing.T) {
+	tests := []struct {
+		configJSON string
+		network    string
+		symbol     string
+		decimals   int
+		denom      string
+		endpoint   string
+	}{
+		{`{}`, "mainnet", "SOAR", 6, "usoar", Networks["mainnet"].EpochEndpoint},
+		{`{"network": "testnet"}`, "testnet", "SOAR", 6, "usoar", Networks["testnet"].EpochEndpoint},
+		// explicit settings win over the network's
+		{`{"network": "testnet", "token_decimals": 9, "expected_denom": "nsoar", "epoch_endpoint": "http://localhost:1317/epoch"}`,
+			"testnet", "SOAR", 9, "nsoar", "http://localhost:1317/epoch"},
+	}
+	for _, tt := range tests {
+		cfg, err := loadConfigJSON(t, tt.configJSON)
+		if err != nil {
+			t.Errorf("%s: %v", tt.configJSON, err)
+			continue
+		}
+		if cfg.Network != tt.network || cfg.TokenSymbol() != tt.symbol || cfg.TokenDecimals != tt.decimals ||
+			cfg.ExpectedDenom != tt.denom || cfg.EpochEndpoint != tt.endpoint {
+			t.Errorf("%s: network %s, symbol %s, decimals %d, denom %s, endpoint %s; want %s, %s, %d, %s, %s",
+				tt.configJSON, cfg.Network, cfg.TokenSymbol(), cfg.TokenDecimals, cfg.ExpectedDenom, cfg.EpochEndpoint,
+				tt.network, tt.symbol, tt.decimals, tt.denom, tt.endpoint)
+		}
+	}
+
+	if mainnet, testnet := Networks["mainnet"].EpochEndpoint, Networks["testnet"].EpochEndpoint; mainnet == testnet {
+		t.Errorf("mainnet and testnet share the epoch endpoint %s", mainnet)
+	}
+
+	if _, err := loadConfigJSON(t, `{"network": "devnet"}`); err == nil || !strings.Contains(err.Error(), `unknown network "devnet"`) {
+		t.Errorf("loading an unknown network returned %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "soarchain_observer"
+
+var (
+	// Reconnections counts successful WebSocket reconnections.
+	Reconnections = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ws_reconnections_total",
+		Help:      "Number of successful WebSocket reconnections.",
+	})
+
+	// ReconnectionFailures counts failed WebSocket reconnection attempts.
+	ReconnectionFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ws_reconnection_failures_total",
+		Help:      "Number of failed WebSocket reconnection attempts.",
+	})
+
+	// ActiveMiners is the number of distinct wallets that earned within the
+	// trailing window given by the period label.
+	ActiveMiners = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_miners",
+		Help:      "Number of distinct wallets with earnings in the trailing period.",
+	}, []string{"period"})
+
+	// EpochUpserts counts epoch_earnings upserts by identifier and outcome
+	// ("created", "updated" or "error").
+	EpochUpserts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "epoch_upserts_total",
+		Help:      "Number of epoch earnings upserts by outcome.",
+	}, []string{"identifier", "outcome"})
+
+	// MalformedMessages counts WebSocket messages that couldn't be used, by
+	// reason ("oversized", "invalid_json" or "invalid_client_data").
+	MalformedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "malformed_messages_total",
+		Help:      "Number of WebSocket messages that couldn't be parsed, by reason.",
+	}, []string{"reason"})
+
+	// NewClients counts clients seen for the first time.
+	NewClients = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "new_clients_total",
+		Help:      "Number of clients seen for the first time.",
+	})
+
+	// IngestionLag is how far the latest stored earning is behind now.
+	IngestionLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ingestion_lag_seconds",
+		Help:      "Seconds since the timestamp of the latest stored earning.",
+	})
+)
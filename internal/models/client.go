@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Client struct {
 	Address               string `gorm:"primaryKey"`
@@ -8,4 +12,23 @@ type Client struct {
 	SolanaAddress         string `gorm:"index"`
 	TotalLifetimeEarnings int64
 	LastChallengeTime     time.Time `gorm:"index"` // New field
+
+	// CreatedAt is the time of the client's first recorded challenge, which
+	// for backfilled clients is before the row was inserted
+	CreatedAt time.Time
+	// ChallengeCount is the number of challenges the client was rewarded for
+	ChallengeCount int64 `gorm:"not null;default:0"`
+
+	// DeletedAt is set once the client hasn't been challenged for the
+	// configured inactivity period; it is cleared when it shows up again.
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// EarningsAddress is the key the client's earnings rows are stored under:
+// its solana address, or its core address until one is known.
+func (c *Client) EarningsAddress() string {
+	if c.SolanaAddress != "" {
+		return c.SolanaAddress
+	}
+	return c.Address
 }
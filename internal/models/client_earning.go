@@ -5,8 +5,13 @@ import (
 )
 
 type ClientEarning struct {
-	ID            uint   `gorm:"primaryKey"`
-	ClientAddress string `gorm:"index"`
-	Earnings      int64
-	Timestamp     time.Time `gorm:"index"`
+	ID uint `gorm:"primaryKey"`
+	// (client_address, timestamp) serves the per-wallet period queries
+	ClientAddress string `gorm:"index:idx_client_earnings_address_time,priority:1"`
+	// CoreAddress is the client that earned the row, which ClientAddress
+	// doesn't tell once several clients share a solana address. It is empty
+	// on rows recorded before it was tracked.
+	CoreAddress string
+	Earnings    int64
+	Timestamp   time.Time `gorm:"index;index:idx_client_earnings_address_time,priority:2"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// EpochArchive is the compact form old EpochEarnings rows are rolled up
+// into: one row per client, identifier and epoch, without the surrogate id,
+// bookkeeping timestamps or secondary indexes of the live table. The start
+// time is part of the key so an epoch number reused after a chain reset
+// doesn't collide with the archived epoch.
+type EpochArchive struct {
+	ClientAddress string    `gorm:"primaryKey"`
+	Identifier    string    `gorm:"primaryKey"`
+	EpochNumber   int64     `gorm:"primaryKey"`
+	StartTime     time.Time `gorm:"primaryKey"`
+	EndTime       time.Time
+	TotalEarnings int64
+}
+
+func (EpochArchive) TableName() string {
+	return "epoch_archive"
+}
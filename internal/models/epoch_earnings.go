@@ -5,9 +5,11 @@ package models
 import "time"
 
 type EpochEarnings struct {
-	ID            uint      `gorm:"primaryKey"`
-	ClientAddress string    `gorm:"index"`
-	EpochNumber   int64     `gorm:"index"` // e.g. "33"
+	ID uint `gorm:"primaryKey"`
+	// (client_address, identifier, epoch_number) serves the per-wallet reward queries
+	ClientAddress string    `gorm:"index:idx_epoch_earnings_address_identifier_epoch,priority:1"`
+	Identifier    string    `gorm:"not null;default:day;index:idx_epoch_earnings_address_identifier_epoch,priority:2"` // e.g. "day", "week"
+	EpochNumber   int64     `gorm:"index;index:idx_epoch_earnings_address_identifier_epoch,priority:3"`                // e.g. "33"
 	StartTime     time.Time // "2025-01-16T09:04:54Z"
 	EndTime       time.Time // StartTime + 86400s
 	TotalEarnings int64
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PriceHistory is the USD price of a token at a point in time, recorded at
+// epoch boundaries so past rewards can be valued at the price of the time.
+type PriceHistory struct {
+	ID        uint      `gorm:"primaryKey"`
+	Symbol    string    `gorm:"index:idx_price_history_symbol_time,priority:1"`
+	Timestamp time.Time `gorm:"index:idx_price_history_symbol_time,priority:2"`
+	USD       float64
+}
+
+func (PriceHistory) TableName() string {
+	return "price_history"
+}
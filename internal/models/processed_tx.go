@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ProcessedTx records the hash of every runner_challenge tx whose earnings
+// have been stored, so replays (backfill, reconnects) don't double count.
+type ProcessedTx struct {
+	Hash      string `gorm:"primaryKey"`
+	Height    int64  `gorm:"index"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// StatusTransition records a miner going Up or Down. Transitions are keyed
+// by the client's core address, which unlike the solana address is known
+// from the first challenge.
+type StatusTransition struct {
+	ID            uint      `gorm:"primaryKey"`
+	ClientAddress string    `gorm:"index:idx_status_transitions_address_time,priority:1"`
+	Status        string    // "Up" or "Down"
+	Timestamp     time.Time `gorm:"index:idx_status_transitions_address_time,priority:2"`
+}
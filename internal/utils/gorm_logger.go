@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger routes GORM's logs through a structured logger. Queries slower
+// than SlowThreshold are logged at warn with their SQL and duration; failed
+// queries (other than "record not found") at error.
+type GormLogger struct {
+	Logger        *slog.Logger
+	SlowThreshold time.Duration
+	LogLevel      gormlogger.LogLevel
+}
+
+// NewGormLogger returns a GormLogger logging warnings and errors.
+func NewGormLogger(logger *slog.Logger, slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{
+		Logger:        logger,
+		SlowThreshold: slowThreshold,
+		LogLevel:      gormlogger.Warn,
+	}
+}
+
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.LogLevel = level
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.LogLevel >= gormlogger.Info {
+		l.Logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.LogLevel >= gormlogger.Warn {
+		l.Logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.LogLevel >= gormlogger.Error {
+		l.Logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.LogLevel <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.LogLevel >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.Logger.ErrorContext(ctx, "query failed",
+			slog.String("error", err.Error()),
+			slog.Duration("duration", elapsed),
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+		)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.LogLevel >= gormlogger.Warn:
+		sql, rows := fc()
+		l.Logger.WarnContext(ctx, "slow query",
+			slog.Duration("duration", elapsed),
+			slog.Duration("threshold", l.SlowThreshold),
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+		)
+	case l.LogLevel >= gormlogger.Info:
+		sql, rows := fc()
+		l.Logger.DebugContext(ctx, "query",
+			slog.Duration("duration", elapsed),
+			slog.String("sql", sql),
+			slog.Int64("rows", rows),
+		)
+	}
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePeriod parses a period query value such as "30m", "24h" or "7d".
+// It accepts everything time.ParseDuration does plus a whole-day "d" unit.
+func ParsePeriod(period string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(period, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid period %q", period)
+		}
+		if n <= 0 {
+			return 0, fmt.Errorf("period must be positive: %q", period)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("period must be positive: %q", period)
+	}
+	return d, nil
+}
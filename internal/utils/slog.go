@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// GetStructuredLogger returns a JSON logger writing to stdout, for log lines
+// that are meant to be queried by field (e.g. HTTP request logs).
+func GetStructuredLogger() *slog.Logger {
+	return GetStructuredLoggerAt(slog.LevelDebug)
+}
+
+// GetStructuredLoggerAt is GetStructuredLogger dropping the lines below
+// level.
+func GetStructuredLoggerAt(level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
+
+// ParseLogLevel maps "debug", "info", "warn" or "error" to a slog level,
+// defaulting to info.
+func ParseLogLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}